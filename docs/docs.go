@@ -31,7 +31,7 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Type of deduction: personal or k-receipt",
+                        "description": "Type of deduction: personal, k-receipt, home-loan-interest or home-purchase",
                         "name": "type",
                         "in": "path",
                         "required": true
@@ -71,6 +71,164 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/rounding-policy": {
+            "post": {
+                "description": "Change the rounding policy applied when a request does not set one: none, half-up-satang or floor-baht",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Change default rounding policy",
+                "parameters": [
+                    {
+                        "description": "Rounding policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.RoundingPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the updated rounding policy",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RoundingPolicyRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/social-security/{year}": {
+            "post": {
+                "description": "Change the social security contribution rate and monthly wage caps for a tax year",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Change social security rate",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year in Buddhist Era, e.g. 2567",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rate and monthly wage caps",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.SocialSecurityRateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the updated rate",
+                        "schema": {
+                            "$ref": "#/definitions/tax.SocialSecurityRateRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tax-brackets/{year}": {
+            "post": {
+                "description": "Replace the tax brackets used for a tax year. The last bracket must leave max at 0 for no upper limit, so that all income above its min is taxed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Change tax brackets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year in Buddhist Era, e.g. 2567",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tax brackets ordered by min",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/tax.TaxBracket"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the updated brackets",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/tax.TaxBracket"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
         "/tax/calculations": {
             "post": {
                 "description": "Calculate tax from request based on the provided data",
@@ -93,6 +251,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/tax.TaxRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en to include an explanation",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -117,12 +281,130 @@ const docTemplate = `{
                 }
             }
         },
+        "/tax/calculations/batch": {
+            "post": {
+                "description": "Calculate tax for many rows shaped like the rows of an uploaded CSV. Results are returned as JSON, as CSV with Accept: text/csv or format=csv, or as .xlsx with format=xlsx",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate tax for a batch",
+                "parameters": [
+                    {
+                        "description": "Rows to calculate",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxBatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or xlsx to download the results as a file",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add one column per tax level to CSV and .xlsx results",
+                        "name": "taxLevels",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the tax levels and allowed deductions of each row in JSON results",
+                        "name": "breakdown",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the calculated tax",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxCSVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/diff": {
+            "post": {
+                "description": "Compare two tax requests and attribute the change in tax to income, each allowance, brackets and WHT",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Explain why tax changed",
+                "parameters": [
+                    {
+                        "description": "Before and after tax data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxDiffRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the structured diff",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
         "/tax/calculations/upload-csv": {
             "post": {
-                "description": "Calculate tax based on the data provided in a CSV file",
+                "description": "Calculate tax based on the data provided in a CSV, TSV or semicolon-delimited file, or in the first sheet of an .xlsx workbook. The format is detected from the content, whatever the file name. Columns are matched by header name: totalIncome and wht are required, an id or employeeId column identifies each row, allowance columns (donation, k-receipt, home-loan-interest, home-purchase, social-security) are optional and other columns are returned as is",
                 "consumes": [
                     "multipart/form-data"
                 ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
                 "tags": [
                     "tax"
                 ],
@@ -130,66 +412,1090 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "file",
-                        "description": "CSV file containing tax data",
+                        "description": "CSV or .xlsx file containing tax data",
                         "name": "taxFile",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sheet of the .xlsx workbook to read instead of the first one",
+                        "name": "sheet",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the whole file when any row is invalid instead of reporting the invalid rows",
+                        "name": "strict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Calculate a CSV file row by row while it is uploaded and stream the results as NDJSON, or as CSV with format=csv",
+                        "name": "stream",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or xlsx to download the results as a file",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add one column per tax level to CSV and .xlsx results",
+                        "name": "taxLevels",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the tax levels and allowed deductions of each row in JSON results",
+                        "name": "breakdown",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the calculated tax and the rows that could not be calculated",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxCSVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/corporate/calculations": {
+            "post": {
+                "description": "Calculate corporate income tax on net profit with SME tiered rates when paid-up capital and revenue are within the thresholds",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "corporate"
+                ],
+                "summary": "Calculate corporate income tax",
+                "parameters": [
+                    {
+                        "description": "Corporate tax data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.CorporateTaxRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the corporate tax calculation",
+                        "schema": {
+                            "$ref": "#/definitions/tax.CorporateTaxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/gross-up/calculations": {
+            "post": {
+                "description": "Iterate from the intended net income to the grossed-up income and tax when the employer pays the tax",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate gross-up for employer-borne tax",
+                "parameters": [
+                    {
+                        "description": "Net income data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.GrossUpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the gross-up calculation",
+                        "schema": {
+                            "$ref": "#/definitions/tax.GrossUpResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/jobs": {
+            "post": {
+                "description": "Upload a CSV or .xlsx file with the same rules as /tax/calculations/upload-csv. The rows are stored and calculated in the background; poll /tax/jobs/{id} for progress",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Create a tax batch job",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV or .xlsx file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sheet of the .xlsx workbook to read instead of the first one",
+                        "name": "sheet",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the whole file when any row is invalid instead of reporting the invalid rows",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Returns the queued job",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/jobs/{id}": {
+            "get": {
+                "description": "Get the status and progress of a tax batch job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get a tax batch job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the job",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxJob"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/jobs/{id}/results": {
+            "get": {
+                "description": "Download the results of a finished tax batch job as JSON, as CSV with Accept: text/csv or format=csv, or as .xlsx with format=xlsx",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get tax batch job results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or xlsx to download the results as a file",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add one column per tax level to CSV and .xlsx results",
+                        "name": "taxLevels",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the tax levels and allowed deductions of each row in JSON results",
+                        "name": "breakdown",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the calculated tax",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxCSVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Job is not done",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/projections": {
+            "post": {
+                "description": "Project tax year by year from a base request with income growth and planned allowance changes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Project tax over several years",
+                "parameters": [
+                    {
+                        "description": "Projection data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.ProjectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the year-by-year projection",
+                        "schema": {
+                            "$ref": "#/definitions/tax.ProjectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/provisional/calculations": {
+            "post": {
+                "description": "Calculate provisional tax on January to June income with halved deductions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate half-year provisional tax (PND 94)",
+                "parameters": [
+                    {
+                        "description": "Half-year tax data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the provisional tax calculation",
+                        "schema": {
+                            "$ref": "#/definitions/tax.TaxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/retirement/calculations": {
+            "post": {
+                "description": "Calculate tax on a severance or provident fund lump sum taxed separately from other income",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate tax on a retirement lump sum",
+                "parameters": [
+                    {
+                        "description": "Retirement lump sum data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.RetirementTaxRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the retirement tax calculation",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RetirementTaxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/sensitivity": {
+            "post": {
+                "description": "Sweep total income over a range for one deduction profile and return tax, marginal and effective rates with bracket breakpoints",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate a tax sensitivity curve",
+                "parameters": [
+                    {
+                        "description": "Income range and deduction profile",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.SensitivityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the sensitivity curve",
+                        "schema": {
+                            "$ref": "#/definitions/tax.SensitivityResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/withholdings": {
+            "post": {
+                "description": "Look up the withholding rate for a payment type and calculate the tax to withhold",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "withholding"
+                ],
+                "summary": "Calculate withholding tax on a payment",
+                "parameters": [
+                    {
+                        "description": "Payment data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.WithholdingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the withholding calculation",
+                        "schema": {
+                            "$ref": "#/definitions/tax.WithholdingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/withholdings/upload-csv": {
+            "post": {
+                "description": "Calculate withholding tax for every payment in a CSV file with paymentType and amount columns",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "withholding"
+                ],
+                "summary": "Calculate withholding tax from CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing payments",
+                        "name": "paymentFile",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the withholding calculations and totals",
+                        "schema": {
+                            "$ref": "#/definitions/tax.WithholdingCSVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "tax.Allowance": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "coBorrowers": {
+                    "type": "integer"
+                },
+                "monthlyWages": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "months": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tax.AllowanceChange": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string"
+                },
+                "allowed": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                },
+                "amount": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                }
+            }
+        },
+        "tax.AllowanceDetail": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string"
+                },
+                "allowed": {
+                    "type": "number"
+                },
+                "amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.AllowancePlan": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.Allowance"
+                    }
+                },
+                "taxYear": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tax.AmountChange": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "type": "number"
+                },
+                "before": {
+                    "type": "number"
+                },
+                "change": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.Breakpoint": {
+            "type": "object",
+            "properties": {
+                "rate": {
+                    "type": "number"
+                },
+                "taxableIncome": {
+                    "type": "number"
+                },
+                "totalIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.CorporateTaxRequest": {
+            "type": "object",
+            "properties": {
+                "locale": {
+                    "type": "string"
+                },
+                "netProfit": {
+                    "type": "number"
+                },
+                "paidUpCapital": {
+                    "type": "number"
+                },
+                "revenue": {
+                    "type": "number"
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.CorporateTaxResponse": {
+            "type": "object",
+            "properties": {
+                "netProfit": {
+                    "type": "number"
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
+                "sme": {
+                    "type": "boolean"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxLevel"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.DeductionRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.Err": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.GrossUpRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.Allowance"
+                    }
+                },
+                "netIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.GrossUpResponse": {
+            "type": "object",
+            "properties": {
+                "converged": {
+                    "type": "boolean"
+                },
+                "grossIncome": {
+                    "type": "number"
+                },
+                "iterations": {
+                    "type": "integer"
+                },
+                "netIncome": {
+                    "type": "number"
+                },
+                "taxResponse": {
+                    "$ref": "#/definitions/tax.TaxResponse"
+                }
+            }
+        },
+        "tax.IncomeSource": {
+            "type": "object",
+            "properties": {
+                "income": {
+                    "type": "number"
+                },
+                "payerTaxId": {
+                    "type": "string"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.ProjectionRequest": {
+            "type": "object",
+            "properties": {
+                "allowancePlans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowancePlan"
+                    }
+                },
+                "incomeGrowthRate": {
+                    "type": "number"
+                },
+                "taxRequest": {
+                    "$ref": "#/definitions/tax.TaxRequest"
+                },
+                "years": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tax.ProjectionResponse": {
+            "type": "object",
+            "properties": {
+                "projections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.ProjectionYear"
+                    }
+                }
+            }
+        },
+        "tax.ProjectionYear": {
+            "type": "object",
+            "properties": {
+                "cumulativeTax": {
+                    "type": "number"
+                },
+                "effectiveRate": {
+                    "type": "number"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxYear": {
+                    "type": "integer"
+                },
+                "totalIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.RetirementTaxRequest": {
+            "type": "object",
+            "properties": {
+                "locale": {
+                    "type": "string"
+                },
+                "lumpSum": {
+                    "type": "number"
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
+                "wht": {
+                    "type": "number"
+                },
+                "yearsOfService": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tax.RetirementTaxResponse": {
+            "type": "object",
+            "properties": {
+                "halfDeduction": {
+                    "type": "number"
+                },
+                "lumpSum": {
+                    "type": "number"
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
+                "serviceDeduction": {
+                    "type": "number"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxLevel"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number"
+                },
+                "taxableIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.RoundingPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "roundingPolicy": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.SensitivityPoint": {
+            "type": "object",
+            "properties": {
+                "effectiveRate": {
+                    "type": "number"
+                },
+                "marginalRate": {
+                    "type": "number"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "totalIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.SensitivityRequest": {
+            "type": "object",
+            "properties": {
+                "maxIncome": {
+                    "type": "number"
+                },
+                "minIncome": {
+                    "type": "number"
+                },
+                "step": {
+                    "type": "number"
+                },
+                "taxRequest": {
+                    "$ref": "#/definitions/tax.TaxRequest"
+                }
+            }
+        },
+        "tax.SensitivityResponse": {
+            "type": "object",
+            "properties": {
+                "breakpoints": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.Breakpoint"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Returns the calculated tax",
-                        "schema": {
-                            "$ref": "#/definitions/tax.TaxCSVResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/tax.Err"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/tax.Err"
-                        }
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.SensitivityPoint"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "tax.Allowance": {
+        },
+        "tax.SocialSecurityRateRequest": {
             "type": "object",
             "properties": {
-                "allowanceType": {
+                "maxMonthlyWage": {
+                    "type": "number"
+                },
+                "minMonthlyWage": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.TaxAttribution": {
+            "type": "object",
+            "properties": {
+                "cause": {
                     "type": "string"
                 },
-                "amount": {
+                "taxEffect": {
                     "type": "number"
                 }
             }
         },
-        "tax.DeductionRequest": {
+        "tax.TaxBatchRequest": {
             "type": "object",
             "properties": {
-                "amount": {
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxCSVRequest"
+                    }
+                }
+            }
+        },
+        "tax.TaxBracket": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "rate": {
                     "type": "number"
                 }
             }
         },
-        "tax.Err": {
+        "tax.TaxCSVNormalization": {
             "type": "object",
             "properties": {
-                "message": {
+                "column": {
+                    "type": "string"
+                },
+                "normalized": {
+                    "type": "string"
+                },
+                "original": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.TaxCSVRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.Allowance"
+                    }
+                },
+                "columns": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
                     "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "normalizations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxCSVNormalization"
+                    }
+                },
+                "totalIncome": {
+                    "type": "number"
+                },
+                "wht": {
+                    "type": "number"
                 }
             }
         },
         "tax.TaxCSVResponse": {
             "type": "object",
             "properties": {
+                "encoding": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxCSVRowError"
+                    }
+                },
                 "taxes": {
                     "type": "array",
                     "items": {
@@ -201,9 +1507,39 @@ const docTemplate = `{
         "tax.TaxCSVResponseDetail": {
             "type": "object",
             "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceDetail"
+                    }
+                },
+                "columns": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "normalizations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxCSVNormalization"
+                    }
+                },
                 "tax": {
                     "type": "number"
                 },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxLevel"
+                    }
+                },
                 "taxRefund": {
                     "type": "number"
                 },
@@ -212,12 +1548,119 @@ const docTemplate = `{
                 }
             }
         },
+        "tax.TaxCSVRowError": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.TaxDiffRequest": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "$ref": "#/definitions/tax.TaxRequest"
+                },
+                "before": {
+                    "$ref": "#/definitions/tax.TaxRequest"
+                }
+            }
+        },
+        "tax.TaxDiffResponse": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceChange"
+                    }
+                },
+                "attributions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxAttribution"
+                    }
+                },
+                "tax": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                },
+                "taxLevels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.TaxLevelChange"
+                    }
+                },
+                "taxableIncome": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                },
+                "totalIncome": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                },
+                "wht": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                }
+            }
+        },
+        "tax.TaxJob": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "encoding": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errorRows": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "processedRows": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "totalRows": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
         "tax.TaxLevel": {
             "type": "object",
             "properties": {
+                "credit": {
+                    "type": "number"
+                },
                 "level": {
                     "type": "string"
                 },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "netTax": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                },
                 "tax": {
                     "type": "number"
                 },
@@ -226,6 +1669,17 @@ const docTemplate = `{
                 }
             }
         },
+        "tax.TaxLevelChange": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string"
+                },
+                "tax": {
+                    "$ref": "#/definitions/tax.AmountChange"
+                }
+            }
+        },
         "tax.TaxRequest": {
             "type": "object",
             "properties": {
@@ -235,6 +1689,24 @@ const docTemplate = `{
                         "$ref": "#/definitions/tax.Allowance"
                     }
                 },
+                "incomeSources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.IncomeSource"
+                    }
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "provisionalTaxPaid": {
+                    "type": "number"
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
+                "taxYear": {
+                    "type": "integer"
+                },
                 "totalIncome": {
                     "type": "number"
                 },
@@ -246,6 +1718,24 @@ const docTemplate = `{
         "tax.TaxResponse": {
             "type": "object",
             "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceDetail"
+                    }
+                },
+                "explanation": {
+                    "type": "string"
+                },
+                "incomeSources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.IncomeSource"
+                    }
+                },
+                "roundingPolicy": {
+                    "type": "string"
+                },
                 "tax": {
                     "type": "number"
                 },
@@ -259,6 +1749,54 @@ const docTemplate = `{
                     "type": "number"
                 }
             }
+        },
+        "tax.WithholdingCSVResponse": {
+            "type": "object",
+            "properties": {
+                "totalAmount": {
+                    "type": "number"
+                },
+                "totalWht": {
+                    "type": "number"
+                },
+                "withholdings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.WithholdingResponse"
+                    }
+                }
+            }
+        },
+        "tax.WithholdingRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "paymentType": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.WithholdingResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "netPayment": {
+                    "type": "number"
+                },
+                "paymentType": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
         }
     }
 }`
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	_ "github.com/fnk2077/assessment-tax/docs"
@@ -24,7 +25,11 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	p.BatchWorkers, _ = strconv.Atoi(os.Getenv("BATCH_WORKERS"))
 	taxHandler := tax.New(p)
+	maxUploadSize, _ := strconv.ParseInt(os.Getenv("MAX_UPLOAD_SIZE"), 10, 64)
+	maxUploadRows, _ := strconv.Atoi(os.Getenv("MAX_UPLOAD_ROWS"))
+	taxHandler.SetUploadLimits(maxUploadSize, maxUploadRows)
 
 	e := echo.New()
 	e.Use(middleware.Logger())
@@ -37,10 +42,30 @@ func main() {
 
 	e.POST("/tax/calculations", taxHandler.TaxCalculateHandler)
 	e.POST("/tax/calculations/upload-csv", taxHandler.TaxCVSCalculateHandler)
+	e.POST("/tax/calculations/batch", taxHandler.TaxBatchCalculateHandler)
+	e.POST("/tax/calculations/diff", taxHandler.TaxDiffHandler)
+	e.POST("/tax/provisional/calculations", taxHandler.ProvisionalTaxCalculateHandler)
+	e.POST("/tax/retirement/calculations", taxHandler.RetirementTaxCalculateHandler)
+	e.POST("/tax/corporate/calculations", taxHandler.CorporateTaxCalculateHandler)
+	e.POST("/tax/gross-up/calculations", taxHandler.GrossUpCalculateHandler)
+	e.POST("/tax/projections", taxHandler.TaxProjectionHandler)
+	e.POST("/tax/sensitivity", taxHandler.TaxSensitivityHandler)
+	e.POST("/tax/withholdings", taxHandler.WithholdingCalculateHandler)
+	e.POST("/tax/withholdings/upload-csv", taxHandler.WithholdingCSVCalculateHandler)
+	e.POST("/tax/jobs", taxHandler.CreateTaxJobHandler)
+	e.GET("/tax/jobs/:id", taxHandler.TaxJobHandler)
+	e.GET("/tax/jobs/:id/results", taxHandler.TaxJobResultsHandler)
 
 	g := e.Group("/admin")
 	g.Use(middleware.BasicAuth(middlewares.AuthMiddleware))
 	g.POST("/deductions/:type", taxHandler.ChangeDeductionHandler)
+	g.POST("/social-security/:year", taxHandler.ChangeSocialSecurityRateHandler)
+	g.POST("/tax-brackets/:year", taxHandler.ChangeTaxBracketsHandler)
+	g.POST("/rounding-policy", taxHandler.ChangeRoundingPolicyHandler)
+
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go p.RunTaxJobs(jobCtx, time.Second)
 
 	go func() {
 		if err := e.Start(":" + os.Getenv("PORT")); err != nil && err != http.ErrServerClosed {
@@ -52,6 +77,7 @@ func main() {
 	signal.Notify(shutdown, os.Interrupt)
 	<-shutdown
 	fmt.Println("shutting down the server")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := e.Shutdown(ctx); err != nil {
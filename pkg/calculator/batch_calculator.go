@@ -0,0 +1,87 @@
+package calculator
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// batchChunkSize is how many rows a worker takes at a time, so that workers
+// do not contend on every row.
+const batchChunkSize = 256
+
+// TaxCSVCalculator calculates one row of a batch upload.
+func TaxCSVCalculator(req tax.TaxCSVRequest, config tax.TaxYearConfig) tax.TaxCSVResponseDetail {
+	var taxCSVResponseDetail tax.TaxCSVResponseDetail
+	taxRequest := tax.TaxRequest{
+		TotalIncome: req.TotalIncome,
+		Wht:         req.Wht,
+		Allowances:  req.Allowances,
+	}
+	taxResponse := TaxCalculatorWithConfig(taxRequest, config)
+
+	taxCSVResponseDetail.ID = req.ID
+	taxCSVResponseDetail.Line = req.Line
+	taxCSVResponseDetail.TotalIncome = req.TotalIncome
+	taxCSVResponseDetail.Columns = req.Columns
+	taxCSVResponseDetail.TaxLevels = taxResponse.TaxLevels
+	taxCSVResponseDetail.Allowances = taxResponse.Allowances
+	taxCSVResponseDetail.Normalizations = req.Normalizations
+
+	if (taxResponse.Tax >= 0) && (taxResponse.TaxRefund == 0.0) {
+		taxCSVResponseDetail.Tax = taxResponse.Tax
+	} else {
+		taxCSVResponseDetail.TaxRefund = taxResponse.TaxRefund
+	}
+	return taxCSVResponseDetail
+}
+
+// TaxCSVBatchCalculator calculates every row of a batch with the same config
+// on a pool of workers, one per CPU when workers is 0 or less. Results are in
+// the order of reqs. It stops early with the context error when ctx is done.
+func TaxCSVBatchCalculator(ctx context.Context, reqs []tax.TaxCSVRequest, config tax.TaxYearConfig, workers int) ([]tax.TaxCSVResponseDetail, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	details := make([]tax.TaxCSVResponseDetail, len(reqs))
+
+	// Each worker writes only to the indexes of the chunks it receives, so
+	// the results need no locking and keep the input order.
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := start + batchChunkSize
+				if end > len(reqs) {
+					end = len(reqs)
+				}
+				for i := start; i < end; i++ {
+					details[i] = TaxCSVCalculator(reqs[i], config)
+				}
+			}
+		}()
+	}
+
+	var err error
+send:
+	for start := 0; start < len(reqs); start += batchChunkSize {
+		select {
+		case chunks <- start:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break send
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
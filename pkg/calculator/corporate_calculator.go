@@ -0,0 +1,44 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// DefaultCorporateTaxConfig is the corporate income tax setup used when none
+// is configured. A company with paid-up capital and revenue within both SME
+// thresholds pays the tiered SME rates; any other company pays a flat 20%.
+var DefaultCorporateTaxConfig = tax.CorporateTaxConfig{
+	MaxSMEPaidUpCapital: 5000000.0,
+	MaxSMERevenue:       30000000.0,
+	SMEBrackets: []tax.TaxBracket{
+		{Min: 0, Max: 300000, Rate: 0},
+		{Min: 300000, Max: 3000000, Rate: 0.15},
+		{Min: 3000000, Max: math.MaxFloat64, Rate: 0.20},
+	},
+	GeneralBrackets: []tax.TaxBracket{
+		{Min: 0, Max: math.MaxFloat64, Rate: 0.20},
+	},
+}
+
+func CorporateTaxCalculator(req tax.CorporateTaxRequest, config tax.CorporateTaxConfig) tax.CorporateTaxResponse {
+	var corporateTaxResponse tax.CorporateTaxResponse
+	sme := req.PaidUpCapital <= config.MaxSMEPaidUpCapital && req.Revenue <= config.MaxSMERevenue
+
+	brackets := config.GeneralBrackets
+	if sme {
+		brackets = config.SMEBrackets
+	}
+	_, taxLevels := bracketTax(req.NetProfit, brackets, req.Locale)
+
+	roundingPolicy := resolveRoundingPolicy(req.RoundingPolicy, config.RoundingPolicy)
+
+	corporateTaxResponse.SME = sme
+	corporateTaxResponse.NetProfit = req.NetProfit
+	_, corporateTaxResponse.Tax, corporateTaxResponse.TaxRefund = settleTax(taxLevels, req.NetProfit, req.Wht, roundingPolicy)
+	corporateTaxResponse.TaxLevels = taxLevels
+	corporateTaxResponse.RoundingPolicy = roundingPolicy
+
+	return corporateTaxResponse
+}
@@ -0,0 +1,161 @@
+package calculator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// TaxDiffCalculator explains how tax moves from one request to another.
+//
+// Tax is reported as the net amount payable, negative for a refund. The
+// difference is attributed step by step: the income change is applied first,
+// then each allowance change in the order allowances first appear, each step
+// priced with the brackets of the before year. Any remainder comes from the
+// after year using different brackets, and the WHT step covers every credit
+// (WHT, income source WHT and provisional tax paid). The steps are priced
+// before rounding, so whatever the rounding policy adds or takes away is
+// attributed to rounding and the attributions add up to the change in tax.
+// Tax levels are matched by their bounds rather than their position.
+func TaxDiffCalculator(req tax.TaxDiffRequest, beforeConfig, afterConfig tax.TaxYearConfig) tax.TaxDiffResponse {
+	var taxDiffResponse tax.TaxDiffResponse
+	before := TaxCalculatorWithConfig(req.Before, beforeConfig)
+	after := TaxCalculatorWithConfig(req.After, afterConfig)
+	beforeIncome, beforeCredit := incomeAndCredit(req.Before)
+	afterIncome, afterCredit := incomeAndCredit(req.After)
+	beforeTaxable := beforeIncome - allowedTotal(before)
+	afterTaxable := afterIncome - allowedTotal(after)
+
+	taxDiffResponse.TotalIncome = amountChange(beforeIncome, afterIncome)
+	taxDiffResponse.TaxableIncome = amountChange(beforeTaxable, afterTaxable)
+	taxDiffResponse.Wht = amountChange(beforeCredit, afterCredit)
+	taxDiffResponse.Tax = amountChange(before.Tax-before.TaxRefund, after.Tax-after.TaxRefund)
+
+	taxDiffResponse.TaxLevels = taxLevelChanges(before.TaxLevels, after.TaxLevels)
+
+	var allowanceTypes []string
+	beforeAllowances := map[string]tax.AllowanceDetail{}
+	afterAllowances := map[string]tax.AllowanceDetail{}
+	collect := func(details []tax.AllowanceDetail, into map[string]tax.AllowanceDetail) {
+		for _, detail := range details {
+			if _, seen := beforeAllowances[detail.AllowanceType]; !seen {
+				if _, seen := afterAllowances[detail.AllowanceType]; !seen {
+					allowanceTypes = append(allowanceTypes, detail.AllowanceType)
+				}
+			}
+			summed := into[detail.AllowanceType]
+			summed.AllowanceType = detail.AllowanceType
+			summed.Amount += detail.Amount
+			summed.Allowed += detail.Allowed
+			into[detail.AllowanceType] = summed
+		}
+	}
+	collect(before.Allowances, beforeAllowances)
+	collect(after.Allowances, afterAllowances)
+
+	brackets := beforeConfig.Brackets
+	taxable := beforeTaxable
+	grossTax := func(taxable float64) float64 {
+		totalTax, _ := bracketTax(taxable, brackets, "")
+		return totalTax
+	}
+	attribute := func(cause string, nextTaxable float64) {
+		taxDiffResponse.Attributions = append(taxDiffResponse.Attributions, tax.TaxAttribution{
+			Cause:     cause,
+			TaxEffect: grossTax(nextTaxable) - grossTax(taxable),
+		})
+		taxable = nextTaxable
+	}
+
+	attribute("totalIncome", taxable+afterIncome-beforeIncome)
+	for _, allowanceType := range allowanceTypes {
+		beforeAllowance := beforeAllowances[allowanceType]
+		afterAllowance := afterAllowances[allowanceType]
+		taxDiffResponse.Allowances = append(taxDiffResponse.Allowances, tax.AllowanceChange{
+			AllowanceType: allowanceType,
+			Amount:        amountChange(beforeAllowance.Amount, afterAllowance.Amount),
+			Allowed:       amountChange(beforeAllowance.Allowed, afterAllowance.Allowed),
+		})
+		attribute("allowance:"+allowanceType, taxable-(afterAllowance.Allowed-beforeAllowance.Allowed))
+	}
+
+	afterGrossTax, _ := bracketTax(afterTaxable, afterConfig.Brackets, "")
+	if bracketEffect := afterGrossTax - grossTax(taxable); bracketEffect != 0 {
+		taxDiffResponse.Attributions = append(taxDiffResponse.Attributions, tax.TaxAttribution{
+			Cause:     "brackets",
+			TaxEffect: bracketEffect,
+		})
+	}
+	taxDiffResponse.Attributions = append(taxDiffResponse.Attributions, tax.TaxAttribution{
+		Cause:     "wht",
+		TaxEffect: -(afterCredit - beforeCredit),
+	})
+
+	attributed := 0.0
+	for _, attribution := range taxDiffResponse.Attributions {
+		attributed += attribution.TaxEffect
+	}
+	if rounding := taxDiffResponse.Tax.Change - attributed; math.Abs(rounding) > roundingEpsilon {
+		taxDiffResponse.Attributions = append(taxDiffResponse.Attributions, tax.TaxAttribution{
+			Cause:     "rounding",
+			TaxEffect: rounding,
+		})
+	}
+
+	return taxDiffResponse
+}
+
+// taxLevelChanges pairs the levels of two calculations by their bounds, in
+// order of the bounds. A level found on one side only, as when the two tax
+// years have different brackets, is compared against 0 and keeps its label.
+func taxLevelChanges(before, after []tax.TaxLevel) []tax.TaxLevelChange {
+	type bounds struct{ min, max float64 }
+	var order []bounds
+	labels := map[bounds]string{}
+	beforeTax := map[bounds]float64{}
+	afterTax := map[bounds]float64{}
+	collect := func(levels []tax.TaxLevel, into map[bounds]float64) {
+		for _, level := range levels {
+			key := bounds{level.Min, level.Max}
+			if _, seen := labels[key]; !seen {
+				order = append(order, key)
+			}
+			labels[key] = level.Level
+			into[key] += level.Tax
+		}
+	}
+	collect(before, beforeTax)
+	collect(after, afterTax)
+
+	// A Max of 0 is the open-ended top level, which sorts last.
+	upper := func(max float64) float64 {
+		if max == 0 {
+			return math.Inf(1)
+		}
+		return max
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].min != order[j].min {
+			return order[i].min < order[j].min
+		}
+		return upper(order[i].max) < upper(order[j].max)
+	})
+
+	changes := make([]tax.TaxLevelChange, 0, len(order))
+	for _, key := range order {
+		changes = append(changes, tax.TaxLevelChange{
+			Level: labels[key],
+			Tax:   amountChange(beforeTax[key], afterTax[key]),
+		})
+	}
+	return changes
+}
+
+func amountChange(before, after float64) tax.AmountChange {
+	return tax.AmountChange{
+		Before: before,
+		After:  after,
+		Change: after - before,
+	}
+}
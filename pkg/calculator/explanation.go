@@ -0,0 +1,143 @@
+package calculator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+type explanationAllowance struct {
+	Name    string
+	Amount  float64
+	Allowed float64
+	Capped  bool
+}
+
+type explanationLevel struct {
+	Level string
+	Rate  float64
+	Tax   float64
+}
+
+type explanationData struct {
+	TotalIncome   float64
+	Allowances    []explanationAllowance
+	TaxableIncome float64
+	Levels        []explanationLevel
+	TotalTax      float64
+	Credit        float64
+	Tax           float64
+	TaxRefund     float64
+}
+
+var allowanceNames = map[string]map[string]string{
+	"en": {
+		"personal":           "Personal allowance",
+		"donation":           "Donation",
+		"k-receipt":          "k-receipt",
+		"home-loan-interest": "Home loan interest",
+		"home-purchase":      "Home purchase",
+		"social-security":    "Social security contribution",
+	},
+	"th": {
+		"personal":           "ค่าลดหย่อนส่วนตัว",
+		"donation":           "เงินบริจาค",
+		"k-receipt":          "ช้อปลดภาษี (k-receipt)",
+		"home-loan-interest": "ดอกเบี้ยเงินกู้ซื้อที่อยู่อาศัย",
+		"home-purchase":      "ค่าซื้อที่อยู่อาศัยใหม่",
+		"social-security":    "เงินสมทบประกันสังคม",
+	},
+}
+
+var explanationFuncs = template.FuncMap{
+	"money":   formatMoney,
+	"percent": func(rate float64) string { return strconv.FormatFloat(rate*100, 'f', -1, 64) + "%" },
+}
+
+var explanationTemplates = map[string]*template.Template{
+	"en": template.Must(template.New("en").Funcs(explanationFuncs).Parse(
+		`Your total income is {{money .TotalIncome}} baht.` +
+			`{{range .Allowances}} {{.Name}}: claimed {{money .Amount}}, allowed {{money .Allowed}}{{if .Capped}} because it is capped at the maximum{{end}}.{{end}}` +
+			` Taxable income after deductions is {{money .TaxableIncome}} baht.` +
+			`{{range .Levels}} Bracket {{.Level}} is taxed at {{percent .Rate}}: {{money .Tax}} baht.{{end}}` +
+			` Total tax is {{money .TotalTax}} baht.` +
+			`{{if .Credit}} Tax already paid through WHT and provisional tax is {{money .Credit}} baht.{{end}}` +
+			`{{if .TaxRefund}} Because tax already paid is more than the tax due, you get a refund of {{money .TaxRefund}} baht.` +
+			`{{else}} Tax payable is {{money .Tax}} baht.{{end}}`)),
+	"th": template.Must(template.New("th").Funcs(explanationFuncs).Parse(
+		`เงินได้ทั้งหมดของคุณคือ {{money .TotalIncome}} บาท` +
+			`{{range .Allowances}} {{.Name}}: ขอลดหย่อน {{money .Amount}} ได้รับ {{money .Allowed}}{{if .Capped}} เนื่องจากเกินเพดานสูงสุด{{end}}{{end}}` +
+			` เงินได้สุทธิหลังหักค่าลดหย่อนคือ {{money .TaxableIncome}} บาท` +
+			`{{range .Levels}} ขั้น {{.Level}} อัตรา {{percent .Rate}}: {{money .Tax}} บาท{{end}}` +
+			` ภาษีทั้งหมด {{money .TotalTax}} บาท` +
+			`{{if .Credit}} ภาษีที่ชำระแล้วจากภาษีหัก ณ ที่จ่ายและภาษีครึ่งปี {{money .Credit}} บาท{{end}}` +
+			`{{if .TaxRefund}} เนื่องจากภาษีที่ชำระแล้วมากกว่าภาษีที่ต้องเสีย คุณจะได้รับเงินคืน {{money .TaxRefund}} บาท` +
+			`{{else}} ภาษีที่ต้องชำระเพิ่ม {{money .Tax}} บาท{{end}}`)),
+}
+
+// explain describes a finished calculation in sentences for language, "th"
+// or "en". Brackets the taxable income does not reach are left out.
+func explain(language string, totalIncome, taxableIncome, totalTax, credit float64, taxResponse tax.TaxResponse) string {
+	tmpl, ok := explanationTemplates[language]
+	if !ok {
+		return ""
+	}
+
+	data := explanationData{
+		TotalIncome:   totalIncome,
+		TaxableIncome: math.Max(taxableIncome, 0),
+		TotalTax:      totalTax,
+		Credit:        credit,
+		Tax:           taxResponse.Tax,
+		TaxRefund:     taxResponse.TaxRefund,
+	}
+	for _, allowance := range taxResponse.Allowances {
+		name, ok := allowanceNames[language][allowance.AllowanceType]
+		if !ok {
+			name = allowance.AllowanceType
+		}
+		data.Allowances = append(data.Allowances, explanationAllowance{
+			Name:    name,
+			Amount:  allowance.Amount,
+			Allowed: allowance.Allowed,
+			Capped:  allowance.Allowed < allowance.Amount,
+		})
+	}
+	for _, level := range taxResponse.TaxLevels {
+		if taxableIncome > level.Min {
+			data.Levels = append(data.Levels, explanationLevel{
+				Level: level.Level,
+				Rate:  level.Rate,
+				Tax:   level.Tax,
+			})
+		}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// formatMoney formats an amount with thousands separators and two decimals.
+func formatMoney(amount float64) string {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', 2, 64)
+	whole, fraction := s[:len(s)-3], s[len(s)-3:]
+
+	var sb strings.Builder
+	if amount < 0 {
+		sb.WriteString("-")
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteRune(digit)
+	}
+	sb.WriteString(fraction)
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+const (
+	grossUpTolerance     = 0.01
+	grossUpMaxIterations = 100
+)
+
+// GrossUpCalculator finds the gross income whose tax, when borne by the
+// employer, leaves the employee with the intended net income. Because the tax
+// paid is itself taxable income, it loops income = net + tax(income) until
+// the income moves by less than a satang. Converged is false when that did
+// not happen within grossUpMaxIterations, and the last income is returned.
+func GrossUpCalculator(req tax.GrossUpRequest, config tax.TaxYearConfig) tax.GrossUpResponse {
+	var grossUpResponse tax.GrossUpResponse
+	grossIncome := req.NetIncome
+	taxRequest := tax.TaxRequest{
+		TotalIncome: grossIncome,
+		Allowances:  req.Allowances,
+	}
+	taxResponse := TaxCalculatorWithConfig(taxRequest, config)
+
+	iterations := 1
+	for ; iterations < grossUpMaxIterations; iterations++ {
+		nextGrossIncome := req.NetIncome + taxResponse.Tax
+		if math.Abs(nextGrossIncome-grossIncome) < grossUpTolerance {
+			grossUpResponse.Converged = true
+			break
+		}
+		grossIncome = nextGrossIncome
+		taxRequest.TotalIncome = grossIncome
+		taxResponse = TaxCalculatorWithConfig(taxRequest, config)
+	}
+
+	grossUpResponse.NetIncome = req.NetIncome
+	grossUpResponse.GrossIncome = grossIncome
+	grossUpResponse.Iterations = iterations
+	grossUpResponse.TaxResponse = taxResponse
+
+	return grossUpResponse
+}
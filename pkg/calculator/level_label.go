@@ -0,0 +1,52 @@
+package calculator
+
+import (
+	"math"
+	"strings"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// Locales accepted for tax level labels. An empty locale is LocaleThai.
+const (
+	LocaleThai         = "th"
+	LocaleEnglish      = "en"
+	LocaleThaiNumerals = "th-numerals"
+)
+
+var thaiDigits = strings.NewReplacer(
+	"0", "๐", "1", "๑", "2", "๒", "3", "๓", "4", "๔",
+	"5", "๕", "6", "๖", "7", "๗", "8", "๘", "9", "๙",
+)
+
+// LevelLabel builds the label of a bracket from its bounds, e.g.
+// "150,001 - 500,000". The lowest bracket starts at its min and the others
+// one baht above it; the open-ended top bracket reads "2,000,001 ขึ้นไป" in
+// Thai or "2,000,001 and above" in English.
+func LevelLabel(bracket tax.TaxBracket, locale string) string {
+	from := bracket.Min
+	if from > 0 {
+		from++
+	}
+
+	var label string
+	if bracket.Max >= math.MaxFloat64 || bracket.Max == 0 {
+		if locale == LocaleEnglish {
+			label = formatInteger(from) + " and above"
+		} else {
+			label = formatInteger(from) + " ขึ้นไป"
+		}
+	} else {
+		label = formatInteger(from) + " - " + formatInteger(bracket.Max)
+	}
+
+	if locale == LocaleThaiNumerals {
+		return thaiDigits.Replace(label)
+	}
+	return label
+}
+
+func formatInteger(amount float64) string {
+	money := formatMoney(amount)
+	return money[:len(money)-3]
+}
@@ -0,0 +1,71 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// TaxProjectionCalculator projects tax over consecutive tax years. configs
+// holds one entry per projected year, in order, starting from the base year.
+// Income grows by IncomeGrowthRate each year and allowance plans apply from
+// their tax year onwards. Credits such as WHT are left out, so each year
+// shows the full tax liability.
+func TaxProjectionCalculator(req tax.ProjectionRequest, configs []tax.TaxYearConfig) tax.ProjectionResponse {
+	var projectionResponse tax.ProjectionResponse
+	allowances := append([]tax.Allowance(nil), req.TaxRequest.Allowances...)
+	cumulativeTax := 0.0
+
+	for i, config := range configs {
+		growth := math.Pow(1+req.IncomeGrowthRate, float64(i))
+		for _, plan := range req.AllowancePlans {
+			if plan.TaxYear == config.TaxYear {
+				allowances = applyAllowancePlan(allowances, plan.Allowances)
+			}
+		}
+
+		taxRequest := tax.TaxRequest{
+			TotalIncome: req.TaxRequest.TotalIncome * growth,
+			TaxYear:     config.TaxYear,
+			Allowances:  allowances,
+		}
+		for _, source := range req.TaxRequest.IncomeSources {
+			taxRequest.TotalIncome += source.Income * growth
+		}
+
+		taxResponse := TaxCalculatorWithConfig(taxRequest, config)
+		cumulativeTax += taxResponse.Tax
+
+		projectionYear := tax.ProjectionYear{
+			TaxYear:       config.TaxYear,
+			TotalIncome:   taxRequest.TotalIncome,
+			Tax:           taxResponse.Tax,
+			CumulativeTax: cumulativeTax,
+		}
+		if taxRequest.TotalIncome > 0 {
+			projectionYear.EffectiveRate = taxResponse.Tax / taxRequest.TotalIncome
+		}
+		projectionResponse.Projections = append(projectionResponse.Projections, projectionYear)
+	}
+
+	return projectionResponse
+}
+
+// applyAllowancePlan replaces allowances of the planned types and adds the
+// ones not claimed before.
+func applyAllowancePlan(allowances []tax.Allowance, planned []tax.Allowance) []tax.Allowance {
+	result := append([]tax.Allowance(nil), allowances...)
+	for _, plan := range planned {
+		replaced := false
+		for i, allowance := range result {
+			if allowance.AllowanceType == plan.AllowanceType {
+				result[i] = plan
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, plan)
+		}
+	}
+	return result
+}
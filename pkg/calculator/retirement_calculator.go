@@ -0,0 +1,33 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// RetirementTaxCalculator taxes a severance or provident fund lump sum
+// separately from other income: 7,000 per year of service is deducted first,
+// then half of what remains, and the rest goes through the brackets of the
+// tax year, DefaultBrackets when it has none.
+func RetirementTaxCalculator(req tax.RetirementTaxRequest, config tax.TaxYearConfig) tax.RetirementTaxResponse {
+	const serviceDeductionPerYear = 7000.0
+	var retirementTaxResponse tax.RetirementTaxResponse
+
+	serviceDeduction := math.Min(serviceDeductionPerYear*float64(req.YearsOfService), req.LumpSum)
+	halfDeduction := (req.LumpSum - serviceDeduction) * 0.5
+	taxableIncome := req.LumpSum - serviceDeduction - halfDeduction
+
+	_, taxLevels := bracketTax(taxableIncome, config.Brackets, req.Locale)
+	roundingPolicy := resolveRoundingPolicy(req.RoundingPolicy, config.RoundingPolicy)
+
+	retirementTaxResponse.LumpSum = req.LumpSum
+	retirementTaxResponse.ServiceDeduction = serviceDeduction
+	retirementTaxResponse.HalfDeduction = halfDeduction
+	retirementTaxResponse.TaxableIncome = taxableIncome
+	_, retirementTaxResponse.Tax, retirementTaxResponse.TaxRefund = settleTax(taxLevels, taxableIncome, req.Wht, roundingPolicy)
+	retirementTaxResponse.TaxLevels = taxLevels
+	retirementTaxResponse.RoundingPolicy = roundingPolicy
+
+	return retirementTaxResponse
+}
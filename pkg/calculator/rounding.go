@@ -0,0 +1,72 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// Rounding policies for tax and refund amounts.
+const (
+	RoundingNone         = "none"
+	RoundingHalfUpSatang = "half-up-satang"
+	RoundingFloorBaht    = "floor-baht"
+)
+
+// roundingEpsilon absorbs float error such as 28999.999999999996 so that
+// flooring does not drop a whole baht.
+const roundingEpsilon = 1e-6
+
+// resolveRoundingPolicy returns the policy a request asked for, else the
+// configured default, else RoundingNone.
+func resolveRoundingPolicy(requested, configured string) string {
+	if requested != "" {
+		return requested
+	}
+	if configured != "" {
+		return configured
+	}
+	return RoundingNone
+}
+
+// roundAmount rounds a non-negative amount with policy. Unknown policies
+// leave the amount unchanged, like RoundingNone.
+func roundAmount(amount float64, policy string) float64 {
+	switch policy {
+	case RoundingHalfUpSatang:
+		return math.Floor(amount*100+0.5+roundingEpsilon) / 100
+	case RoundingFloorBaht:
+		return math.Floor(amount + roundingEpsilon)
+	default:
+		return amount
+	}
+}
+
+// settleTax rounds the tax of each level and their total with policy, then
+// settles credit against the total. The credit is spread over the levels as
+// the policy rounded it, so their NetTax adds up to the returned tax due.
+func settleTax(taxLevels []tax.TaxLevel, taxableIncome, credit float64, policy string) (totalTax, taxDue, taxRefund float64) {
+	for i := range taxLevels {
+		taxLevels[i].Tax = roundAmount(taxLevels[i].Tax, policy)
+		totalTax += taxLevels[i].Tax
+	}
+	totalTax = roundAmount(totalTax, policy)
+
+	if totalTax-credit >= 0 {
+		taxDue = roundAmount(totalTax-credit, policy)
+	} else {
+		taxRefund = roundAmount(-(totalTax - credit), policy)
+	}
+
+	appliedCredit := credit
+	if taxDue > 0 {
+		appliedCredit = totalTax - taxDue
+	}
+	spreadCredit(taxLevels, taxableIncome, appliedCredit, taxRefund)
+	for i := range taxLevels {
+		taxLevels[i].Credit = roundAmount(taxLevels[i].Credit, policy)
+		taxLevels[i].NetTax = roundAmount(taxLevels[i].NetTax, policy)
+	}
+
+	return totalTax, taxDue, taxRefund
+}
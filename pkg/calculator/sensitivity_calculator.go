@@ -0,0 +1,78 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// sensitivityEpsilon absorbs float error in the number of steps, such as
+// 0.3 / 0.1 = 2.9999999999999996.
+const sensitivityEpsilon = 1e-9
+
+// TaxSensitivityCalculator sweeps total income from MinIncome to MaxIncome by
+// Step with one deduction profile and one tax year config. Credits such as
+// WHT are left out so the curve shows the tax liability.
+//
+// Breakpoints are the bracket boundaries, translated to total income with the
+// deductions allowed at MaxIncome, where income-based caps are reached.
+func TaxSensitivityCalculator(req tax.SensitivityRequest, config tax.TaxYearConfig) tax.SensitivityResponse {
+	var sensitivityResponse tax.SensitivityResponse
+	brackets := config.Brackets
+	if len(brackets) == 0 {
+		brackets = DefaultBrackets
+	}
+
+	taxRequest := tax.TaxRequest{
+		TaxYear:    req.TaxRequest.TaxYear,
+		Allowances: req.TaxRequest.Allowances,
+	}
+	// Each point is worked out from its index rather than by adding up steps,
+	// so float error cannot push MaxIncome out of the sweep.
+	points := int(math.Floor((req.MaxIncome-req.MinIncome)/req.Step + sensitivityEpsilon))
+	for i := 0; i <= points; i++ {
+		income := math.Min(req.MinIncome+float64(i)*req.Step, req.MaxIncome)
+		taxRequest.TotalIncome = income
+		taxResponse := TaxCalculatorWithConfig(taxRequest, config)
+		taxableIncome := income - allowedTotal(taxResponse)
+
+		point := tax.SensitivityPoint{
+			TotalIncome:  income,
+			Tax:          taxResponse.Tax,
+			MarginalRate: marginalRate(taxableIncome, brackets),
+		}
+		if income > 0 {
+			point.EffectiveRate = taxResponse.Tax / income
+		}
+		sensitivityResponse.Points = append(sensitivityResponse.Points, point)
+	}
+
+	taxRequest.TotalIncome = req.MaxIncome
+	deductions := allowedTotal(TaxCalculatorWithConfig(taxRequest, config))
+	for _, bracket := range brackets {
+		sensitivityResponse.Breakpoints = append(sensitivityResponse.Breakpoints, tax.Breakpoint{
+			TotalIncome:   bracket.Min + deductions,
+			TaxableIncome: bracket.Min,
+			Rate:          bracket.Rate,
+		})
+	}
+
+	return sensitivityResponse
+}
+
+func allowedTotal(taxResponse tax.TaxResponse) float64 {
+	total := 0.0
+	for _, allowance := range taxResponse.Allowances {
+		total += allowance.Allowed
+	}
+	return total
+}
+
+func marginalRate(taxableIncome float64, brackets []tax.TaxBracket) float64 {
+	for _, bracket := range brackets {
+		if taxableIncome > bracket.Min && taxableIncome <= bracket.Max {
+			return bracket.Rate
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package calculator
+
+import (
+	"math"
+
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+// SocialSecurityContribution works out the deductible social security
+// contribution. Each monthly wage is clamped to the configured wage base
+// before the rate is applied, which caps the contribution per month.
+//
+// Wages come from MonthlyWages when given. Otherwise total income is spread
+// evenly over Months (12 when unset). When neither is given, the claimed
+// Amount is used, capped at a full year of maximum contributions.
+func SocialSecurityContribution(allowance tax.Allowance, totalIncome float64, deductions tax.Deductions) float64 {
+	monthlyContribution := func(wage float64) float64 {
+		wage = math.Min(math.Max(wage, deductions.MinSocialSecurityWage), deductions.MaxSocialSecurityWage)
+		return wage * deductions.SocialSecurityRate
+	}
+
+	if len(allowance.MonthlyWages) > 0 {
+		contribution := 0.0
+		for _, wage := range allowance.MonthlyWages {
+			if wage > 0 {
+				contribution += monthlyContribution(wage)
+			}
+		}
+		return contribution
+	}
+
+	if allowance.Months > 0 || allowance.Amount == 0 {
+		months := allowance.Months
+		if months == 0 {
+			months = 12
+		}
+		if totalIncome <= 0 {
+			return 0
+		}
+		return monthlyContribution(totalIncome/float64(months)) * float64(months)
+	}
+
+	return math.Min(allowance.Amount, monthlyContribution(deductions.MaxSocialSecurityWage)*12)
+}
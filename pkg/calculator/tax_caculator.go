@@ -6,70 +6,207 @@ import (
 	"github.com/fnk2077/assessment-tax/tax"
 )
 
+// DefaultBrackets are the personal income tax brackets used when none are
+// configured for a tax year.
+var DefaultBrackets = []tax.TaxBracket{
+	{Min: 0, Max: 150000, Rate: 0},
+	{Min: 150000, Max: 500000, Rate: 0.10},
+	{Min: 500000, Max: 1000000, Rate: 0.15},
+	{Min: 1000000, Max: 2000000, Rate: 0.20},
+	{Min: 2000000, Max: math.MaxFloat64, Rate: 0.30},
+}
+
+// DefaultDeductions are the deduction caps used when none are configured.
+var DefaultDeductions = tax.Deductions{
+	Personal:            60000.0,
+	MaxKReceipt:         50000.0,
+	MaxDonation:         100000.0,
+	MaxHomeLoanInterest: 100000.0,
+	MaxHomePurchase:     100000.0,
+
+	SocialSecurityRate:    0.05,
+	MinSocialSecurityWage: 1650.0,
+	MaxSocialSecurityWage: 15000.0,
+}
+
 func TaxCalculator(req tax.TaxRequest, personalDeduction, maxKReceiptDeduction float64) tax.TaxResponse {
-	const maxDonationDecuction = 100000.0
-	var taxResponse tax.TaxResponse
-	income := req.TotalIncome - personalDeduction
-
-	if len(req.Allowances) > 0 {
-		for _, allowance := range req.Allowances {
-			if allowance.AllowanceType == "donation" {
-				if allowance.Amount > maxDonationDecuction {
-					income -= maxDonationDecuction
-				} else {
-					income -= allowance.Amount
-				}
-			}
-			if allowance.AllowanceType == "k-receipt" {
-				if allowance.Amount > maxKReceiptDeduction {
-					income -= maxKReceiptDeduction
-				} else {
-					income -= allowance.Amount
-				}
+	deductions := DefaultDeductions
+	deductions.Personal = personalDeduction
+	deductions.MaxKReceipt = maxKReceiptDeduction
+	return TaxCalculatorWithDeductions(req, deductions)
+}
+
+// ProvisionalTaxCalculator estimates the half-year provisional tax (PND 94)
+// on January to June income, with every deduction cap halved.
+func ProvisionalTaxCalculator(req tax.TaxRequest, config tax.TaxYearConfig) tax.TaxResponse {
+	deductions := config.Deductions
+	halved := deductions
+	halved.Personal = deductions.Personal / 2
+	halved.MaxKReceipt = deductions.MaxKReceipt / 2
+	halved.MaxDonation = deductions.MaxDonation / 2
+	halved.MaxHomeLoanInterest = deductions.MaxHomeLoanInterest / 2
+	halved.MaxHomePurchase = deductions.MaxHomePurchase / 2
+
+	// Social security is already worked out per month, so it only needs to
+	// cover six months instead of having its caps halved: an income-based
+	// contribution is spread over six months, and a claimed amount is capped
+	// at six months of the maximum contribution.
+	maxClaim := deductions.MaxSocialSecurityWage * deductions.SocialSecurityRate * 6
+	allowances := make([]tax.Allowance, len(req.Allowances))
+	for i, allowance := range req.Allowances {
+		if allowance.AllowanceType == "social-security" && allowance.Months == 0 && len(allowance.MonthlyWages) == 0 {
+			if allowance.Amount == 0 {
+				allowance.Months = 6
+			} else {
+				allowance.Amount = math.Min(allowance.Amount, maxClaim)
 			}
 		}
+		allowances[i] = allowance
 	}
+	claimed := req.Allowances
+	req.Allowances = allowances
+
+	taxResponse := TaxCalculatorWithConfig(req, tax.TaxYearConfig{
+		TaxYear:        config.TaxYear,
+		Deductions:     halved,
+		Brackets:       config.Brackets,
+		RoundingPolicy: config.RoundingPolicy,
+	})
 
-	taxLevels := []struct {
-		min   float64
-		max   float64
-		rate  float64
-		level string
-	}{
-		{0, 150000, 0, "0 - 150,000"},
-		{150000, 500000, 0.10, "150,001 - 500,000"},
-		{500000, 1000000, 0.15, "500,001 - 1,000,000"},
-		{1000000, 2000000, 0.20, "1,000,001 - 2,000,000"},
-		{2000000, math.MaxFloat64, 0.30, "2,000,001 ขึ้นไป"},
+	// Report what was claimed rather than the capped amount. The personal
+	// allowance comes first in the response.
+	for i, allowance := range claimed {
+		taxResponse.Allowances[i+1].Amount = allowance.Amount
 	}
+	return taxResponse
+}
+
+func TaxCalculatorWithDeductions(req tax.TaxRequest, deductions tax.Deductions) tax.TaxResponse {
+	return TaxCalculatorWithConfig(req, tax.TaxYearConfig{Deductions: deductions})
+}
+
+// TaxCalculatorWithConfig calculates tax with the deductions and brackets of
+// a tax year, falling back to DefaultBrackets when the year has none.
+func TaxCalculatorWithConfig(req tax.TaxRequest, config tax.TaxYearConfig) tax.TaxResponse {
+	var taxResponse tax.TaxResponse
+	deductions := config.Deductions
+	totalIncome, credit := incomeAndCredit(req)
+	taxResponse.IncomeSources = append(taxResponse.IncomeSources, req.IncomeSources...)
+
+	income := totalIncome - deductions.Personal
+	taxResponse.Allowances = append(taxResponse.Allowances, tax.AllowanceDetail{
+		AllowanceType: "personal",
+		Amount:        deductions.Personal,
+		Allowed:       deductions.Personal,
+	})
 
+	for _, allowance := range req.Allowances {
+		allowed := allowedAmount(allowance, totalIncome, deductions)
+		income -= allowed
+		taxResponse.Allowances = append(taxResponse.Allowances, tax.AllowanceDetail{
+			AllowanceType: allowance.AllowanceType,
+			Amount:        allowance.Amount,
+			Allowed:       allowed,
+		})
+	}
+
+	roundingPolicy := resolveRoundingPolicy(req.RoundingPolicy, config.RoundingPolicy)
+	taxResponse.RoundingPolicy = roundingPolicy
+
+	_, taxLevels := bracketTax(income, config.Brackets, req.Locale)
+	totalTax, taxDue, taxRefund := settleTax(taxLevels, income, credit, roundingPolicy)
+	taxResponse.TaxLevels = taxLevels
+	taxResponse.Tax = taxDue
+	taxResponse.TaxRefund = taxRefund
+
+	if req.Language != "" {
+		taxResponse.Explanation = explain(req.Language, totalIncome, income, totalTax, credit, taxResponse)
+	}
+
+	return taxResponse
+}
+
+// spreadCredit fills Credit, NetTax and TaxRefund of each level following the
+// rule documented on tax.TaxLevel.
+func spreadCredit(taxLevels []tax.TaxLevel, taxableIncome, credit, taxRefund float64) {
+	marginal := 0
+	for i := len(taxLevels) - 1; i >= 0; i-- {
+		applied := math.Min(taxLevels[i].Tax, credit)
+		taxLevels[i].Credit = applied
+		taxLevels[i].NetTax = taxLevels[i].Tax - applied
+		credit -= applied
+
+		if marginal == 0 && taxableIncome > taxLevels[i].Min {
+			marginal = i
+		}
+	}
+	if len(taxLevels) > 0 {
+		taxLevels[marginal].TaxRefund = taxRefund
+	}
+}
+
+// incomeAndCredit sums the income and the tax credits (WHT and provisional
+// tax paid) of a request across all of its income sources.
+func incomeAndCredit(req tax.TaxRequest) (float64, float64) {
+	totalIncome := req.TotalIncome
+	credit := req.Wht + req.ProvisionalTaxPaid
+	for _, source := range req.IncomeSources {
+		totalIncome += source.Income
+		credit += source.Wht
+	}
+	return totalIncome, credit
+}
+
+func bracketTax(income float64, brackets []tax.TaxBracket, locale string) (float64, []tax.TaxLevel) {
+	if len(brackets) == 0 {
+		brackets = DefaultBrackets
+	}
+	var taxLevels []tax.TaxLevel
 	totalTax := 0.0
-	for _, bracket := range taxLevels {
-		if income > bracket.min && income <= bracket.max {
-			taxResponse.TaxLevels = append(taxResponse.TaxLevels, tax.TaxLevel{
-				Level: bracket.level,
-				Tax:   ((income - bracket.min) * bracket.rate),
-			})
-			totalTax += ((income - bracket.min) * bracket.rate)
-		} else if income <= bracket.min {
-			taxResponse.TaxLevels = append(taxResponse.TaxLevels, tax.TaxLevel{
-				Level: bracket.level,
-				Tax:   0.0,
-			})
-		} else {
-			taxResponse.TaxLevels = append(taxResponse.TaxLevels, tax.TaxLevel{
-				Level: bracket.level,
-				Tax:   ((bracket.max - bracket.min) * bracket.rate),
-			})
-			totalTax += ((bracket.max - bracket.min) * bracket.rate)
+	for _, bracket := range brackets {
+		levelTax := 0.0
+		if income > bracket.Min && income <= bracket.Max {
+			levelTax = (income - bracket.Min) * bracket.Rate
+		} else if income > bracket.Max {
+			levelTax = (bracket.Max - bracket.Min) * bracket.Rate
+		}
+		totalTax += levelTax
+
+		taxLevel := tax.TaxLevel{
+			Level: LevelLabel(bracket, locale),
+			Min:   bracket.Min,
+			Rate:  bracket.Rate,
+			Tax:   levelTax,
+		}
+		if bracket.Max < math.MaxFloat64 {
+			taxLevel.Max = bracket.Max
 		}
+		taxLevels = append(taxLevels, taxLevel)
 	}
+	return totalTax, taxLevels
+}
 
-	if totalTax-req.Wht >= 0 {
-		taxResponse.Tax = totalTax - req.Wht
-	} else {
-		taxResponse.TaxRefund = -(totalTax - req.Wht)
+func allowedAmount(allowance tax.Allowance, totalIncome float64, deductions tax.Deductions) float64 {
+	if allowance.AllowanceType == "social-security" {
+		return SocialSecurityContribution(allowance, totalIncome, deductions)
 	}
+	return math.Min(allowance.Amount, allowanceCap(allowance, deductions))
+}
 
-	return taxResponse
+func allowanceCap(allowance tax.Allowance, deductions tax.Deductions) float64 {
+	// Co-borrowers on the same loan or property share a single cap.
+	coBorrowers := math.Max(float64(allowance.CoBorrowers), 1)
+
+	switch allowance.AllowanceType {
+	case "donation":
+		return deductions.MaxDonation
+	case "k-receipt":
+		return deductions.MaxKReceipt
+	case "home-loan-interest":
+		return deductions.MaxHomeLoanInterest / coBorrowers
+	case "home-purchase":
+		return deductions.MaxHomePurchase / coBorrowers
+	default:
+		return 0
+	}
 }
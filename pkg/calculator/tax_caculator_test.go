@@ -1,6 +1,10 @@
 package calculator
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
 	"testing"
 
 	"github.com/fnk2077/assessment-tax/tax"
@@ -156,3 +160,1009 @@ func TestTaxCalculator(t *testing.T) {
 		assert.Equal(t, want, got.TaxRefund)
 	})
 }
+
+func TestRetirementTaxCalculator(t *testing.T) {
+
+	t.Run("Lump sum 1,000,000.0 with 10 years of service should return Tax 31,500.0", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        1000000.0,
+			YearsOfService: 10,
+		}
+
+		//Act
+		got := RetirementTaxCalculator(req, tax.TaxYearConfig{})
+
+		//Assert
+		assert.Equal(t, 70000.0, got.ServiceDeduction)
+		assert.Equal(t, 465000.0, got.HalfDeduction)
+		assert.Equal(t, 465000.0, got.TaxableIncome)
+		assert.Equal(t, 31500.0, got.Tax)
+		assert.Equal(t, 31500.0, got.TaxLevels[1].NetTax)
+	})
+
+	t.Run("Wht should be spread over the retirement tax levels", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        1000000.0,
+			YearsOfService: 10,
+			Wht:            1500.0,
+		}
+
+		//Act
+		got := RetirementTaxCalculator(req, tax.TaxYearConfig{})
+
+		//Assert
+		assert.Equal(t, 30000.0, got.Tax)
+		assert.Equal(t, 1500.0, got.TaxLevels[1].Credit)
+		assert.Equal(t, 30000.0, got.TaxLevels[1].NetTax)
+	})
+
+	t.Run("Configured brackets should replace the default brackets", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        1000000.0,
+			YearsOfService: 10,
+		}
+		config := tax.TaxYearConfig{Brackets: []tax.TaxBracket{
+			{Min: 0, Max: 150000, Rate: 0},
+			{Min: 150000, Max: math.MaxFloat64, Rate: 0.2},
+		}}
+
+		//Act
+		got := RetirementTaxCalculator(req, config)
+
+		//Assert
+		assert.Equal(t, 63000.0, got.Tax)
+	})
+
+	t.Run("Rounding policy should apply to retirement tax", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        1000001.0,
+			YearsOfService: 10,
+		}
+
+		//Act
+		got := RetirementTaxCalculator(req, tax.TaxYearConfig{RoundingPolicy: RoundingFloorBaht})
+
+		//Assert
+		assert.Equal(t, 31500.0, got.Tax)
+		assert.Equal(t, 31500.0, got.TaxLevels[1].NetTax)
+		assert.Equal(t, RoundingFloorBaht, got.RoundingPolicy)
+	})
+
+	t.Run("Requested rounding policy should override the default for retirement tax", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        1000001.0,
+			YearsOfService: 10,
+			RoundingPolicy: RoundingNone,
+		}
+
+		//Act
+		got := RetirementTaxCalculator(req, tax.TaxYearConfig{RoundingPolicy: RoundingFloorBaht})
+
+		//Assert
+		assert.InDelta(t, 31500.05, got.Tax, 0.001)
+		assert.Equal(t, RoundingNone, got.RoundingPolicy)
+	})
+
+	t.Run("Service deduction larger than lump sum should return Tax 0.0", func(t *testing.T) {
+		//Arrange
+		req := tax.RetirementTaxRequest{
+			LumpSum:        50000.0,
+			YearsOfService: 10,
+			Wht:            1000.0,
+		}
+
+		//Act
+		got := RetirementTaxCalculator(req, tax.TaxYearConfig{})
+
+		//Assert
+		assert.Equal(t, 50000.0, got.ServiceDeduction)
+		assert.Equal(t, 0.0, got.TaxableIncome)
+		assert.Equal(t, 0.0, got.Tax)
+		assert.Equal(t, 1000.0, got.TaxRefund)
+	})
+}
+
+func TestProvisionalTaxCalculator(t *testing.T) {
+
+	t.Run("Half-year income 300,000.0 should return Tax 12,000.0", func(t *testing.T) {
+		//Arrange
+		want := 12000.0
+		req := tax.TaxRequest{
+			TotalIncome: 300000.0,
+		}
+
+		//Act
+		got := ProvisionalTaxCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+
+	t.Run("Half-year donation 200,000.0 should be capped at 50,000.0", func(t *testing.T) {
+		//Arrange
+		want := 7000.0
+		req := tax.TaxRequest{
+			TotalIncome: 300000.0,
+			Allowances: []tax.Allowance{
+				{
+					AllowanceType: "donation",
+					Amount:        200000.0,
+				},
+			},
+		}
+
+		//Act
+		got := ProvisionalTaxCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+
+	t.Run("Half-year social security claim should be kept and capped at six months", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 300000.0,
+			Allowances: []tax.Allowance{
+				{AllowanceType: "social-security", Amount: 3000.0},
+				{AllowanceType: "social-security", Amount: 9000.0},
+			},
+		}
+
+		//Act
+		got := ProvisionalTaxCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Equal(t, tax.AllowanceDetail{AllowanceType: "social-security", Amount: 3000.0, Allowed: 3000.0}, got.Allowances[1])
+		assert.Equal(t, tax.AllowanceDetail{AllowanceType: "social-security", Amount: 9000.0, Allowed: 4500.0}, got.Allowances[2])
+	})
+}
+
+func TestTaxCalculatorProvisionalTaxPaid(t *testing.T) {
+
+	t.Run("Income 500,000.0 provisional tax paid 10,000.0 WHT 10,000.0 should return Tax 9,000.0", func(t *testing.T) {
+		//Arrange
+		want := 9000.0
+		req := tax.TaxRequest{
+			TotalIncome:        500000.0,
+			Wht:                10000.0,
+			ProvisionalTaxPaid: 10000.0,
+		}
+
+		//Act
+		got := TaxCalculator(req, 60000.0, 50000.0)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+
+	t.Run("Provisional tax paid above annual tax should return TaxRefund", func(t *testing.T) {
+		//Arrange
+		want := 1000.0
+		req := tax.TaxRequest{
+			TotalIncome:        500000.0,
+			ProvisionalTaxPaid: 30000.0,
+		}
+
+		//Act
+		got := TaxCalculator(req, 60000.0, 50000.0)
+
+		//Assert
+		assert.Equal(t, want, got.TaxRefund)
+	})
+}
+
+func TestTaxCalculatorIncomeSources(t *testing.T) {
+
+	t.Run("Two payers 250,000.0 each with WHT 10,000.0 each should return Tax 9,000.0", func(t *testing.T) {
+		//Arrange
+		want := 9000.0
+		req := tax.TaxRequest{
+			IncomeSources: []tax.IncomeSource{
+				{PayerTaxID: "0105551234567", Income: 250000.0, Wht: 10000.0},
+				{PayerTaxID: "0105557654321", Income: 250000.0, Wht: 10000.0},
+			},
+		}
+
+		//Act
+		got := TaxCalculator(req, 60000.0, 50000.0)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+		assert.Equal(t, req.IncomeSources, got.IncomeSources)
+	})
+}
+
+func TestWithholdingCalculator(t *testing.T) {
+
+	t.Run("Rent 20,000.0 at 5% should return WHT 1,000.0", func(t *testing.T) {
+		//Arrange
+		req := tax.WithholdingRequest{
+			PaymentType: "rent",
+			Amount:      20000.0,
+		}
+
+		//Act
+		got := WithholdingCalculator(req, 0.05)
+
+		//Assert
+		assert.Equal(t, 1000.0, got.Wht)
+		assert.Equal(t, 19000.0, got.NetPayment)
+	})
+}
+
+func TestGrossUpCalculator(t *testing.T) {
+
+	t.Run("Net income 500,000.0 should converge to net after tax", func(t *testing.T) {
+		//Arrange
+		req := tax.GrossUpRequest{
+			NetIncome: 500000.0,
+		}
+
+		//Act
+		got := GrossUpCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.InDelta(t, 500000.0, got.GrossIncome-got.TaxResponse.Tax, 0.01)
+		assert.InDelta(t, 32222.22, got.TaxResponse.Tax, 0.01)
+		assert.Greater(t, got.Iterations, 1)
+		assert.True(t, got.Converged)
+	})
+
+	t.Run("Net income below tax threshold should return 1 iteration", func(t *testing.T) {
+		//Arrange
+		req := tax.GrossUpRequest{
+			NetIncome: 200000.0,
+		}
+
+		//Act
+		got := GrossUpCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Equal(t, 200000.0, got.GrossIncome)
+		assert.Equal(t, 1, got.Iterations)
+		assert.True(t, got.Converged)
+	})
+
+	t.Run("Rate close to 100% should stop at the iteration limit and not converge", func(t *testing.T) {
+		//Arrange
+		req := tax.GrossUpRequest{
+			NetIncome: 500000.0,
+		}
+		config := tax.TaxYearConfig{Brackets: []tax.TaxBracket{
+			{Min: 0, Max: math.MaxFloat64, Rate: 0.99},
+		}}
+
+		//Act
+		got := GrossUpCalculator(req, config)
+
+		//Assert
+		assert.Equal(t, grossUpMaxIterations, got.Iterations)
+		assert.False(t, got.Converged)
+	})
+}
+
+func TestTaxCalculatorHomeDeductions(t *testing.T) {
+
+	t.Run("Income 500,000.0 home loan interest 150,000.0 should be capped at 100,000.0", func(t *testing.T) {
+		//Arrange
+		want := 19000.0
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []tax.Allowance{
+				{
+					AllowanceType: "home-loan-interest",
+					Amount:        150000.0,
+				},
+			},
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+
+	t.Run("Two co-borrowers should split the home loan interest cap", func(t *testing.T) {
+		//Arrange
+		want := 24000.0
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []tax.Allowance{
+				{
+					AllowanceType: "home-loan-interest",
+					Amount:        80000.0,
+					CoBorrowers:   2,
+				},
+			},
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+
+	t.Run("Home purchase cap should come from deductions configuration", func(t *testing.T) {
+		//Arrange
+		want := 24000.0
+		deductions := DefaultDeductions
+		deductions.MaxHomePurchase = 50000.0
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []tax.Allowance{
+				{
+					AllowanceType: "home-purchase",
+					Amount:        100000.0,
+				},
+			},
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, deductions)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+}
+
+func TestSocialSecurityContribution(t *testing.T) {
+
+	t.Run("Monthly wages above the cap should return 750.0 per month", func(t *testing.T) {
+		//Arrange
+		allowance := tax.Allowance{
+			AllowanceType: "social-security",
+			MonthlyWages:  []float64{20000.0, 20000.0, 10000.0},
+		}
+
+		//Act
+		got := SocialSecurityContribution(allowance, 0, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 2000.0, got)
+	})
+
+	t.Run("Total income 600,000.0 over 12 months should return 9,000.0", func(t *testing.T) {
+		//Arrange
+		allowance := tax.Allowance{
+			AllowanceType: "social-security",
+		}
+
+		//Act
+		got := SocialSecurityContribution(allowance, 600000.0, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 9000.0, got)
+	})
+
+	t.Run("Total income 120,000.0 over 12 months should return 6,000.0", func(t *testing.T) {
+		//Arrange
+		allowance := tax.Allowance{
+			AllowanceType: "social-security",
+			Months:        12,
+		}
+
+		//Act
+		got := SocialSecurityContribution(allowance, 120000.0, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 6000.0, got)
+	})
+
+	t.Run("Social security allowance should be deducted from income", func(t *testing.T) {
+		//Arrange
+		want := 28100.0
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []tax.Allowance{
+				{
+					AllowanceType: "social-security",
+				},
+			},
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, want, got.Tax)
+	})
+}
+
+func TestTaxProjectionCalculator(t *testing.T) {
+
+	t.Run("Income 500,000.0 growing 10% over 2 years should return cumulative tax 63,000.0", func(t *testing.T) {
+		//Arrange
+		req := tax.ProjectionRequest{
+			TaxRequest:       tax.TaxRequest{TotalIncome: 500000.0},
+			Years:            2,
+			IncomeGrowthRate: 0.1,
+		}
+		configs := []tax.TaxYearConfig{
+			{TaxYear: 2567, Deductions: DefaultDeductions},
+			{TaxYear: 2568, Deductions: DefaultDeductions},
+		}
+
+		//Act
+		got := TaxProjectionCalculator(req, configs)
+
+		//Assert
+		assert.Len(t, got.Projections, 2)
+		assert.Equal(t, 29000.0, got.Projections[0].Tax)
+		assert.InDelta(t, 34000.0, got.Projections[1].Tax, 0.001)
+		assert.InDelta(t, 63000.0, got.Projections[1].CumulativeTax, 0.001)
+		assert.InDelta(t, 0.058, got.Projections[0].EffectiveRate, 0.0001)
+	})
+
+	t.Run("Allowance plan should apply from its tax year onwards", func(t *testing.T) {
+		//Arrange
+		req := tax.ProjectionRequest{
+			TaxRequest: tax.TaxRequest{TotalIncome: 500000.0},
+			Years:      3,
+			AllowancePlans: []tax.AllowancePlan{
+				{
+					TaxYear:    2568,
+					Allowances: []tax.Allowance{{AllowanceType: "donation", Amount: 100000.0}},
+				},
+			},
+		}
+		configs := []tax.TaxYearConfig{
+			{TaxYear: 2567, Deductions: DefaultDeductions},
+			{TaxYear: 2568, Deductions: DefaultDeductions},
+			{TaxYear: 2569, Deductions: DefaultDeductions},
+		}
+
+		//Act
+		got := TaxProjectionCalculator(req, configs)
+
+		//Assert
+		assert.Equal(t, 29000.0, got.Projections[0].Tax)
+		assert.Equal(t, 19000.0, got.Projections[1].Tax)
+		assert.Equal(t, 19000.0, got.Projections[2].Tax)
+		assert.Equal(t, 67000.0, got.Projections[2].CumulativeTax)
+	})
+
+	t.Run("Year with its own brackets should use them", func(t *testing.T) {
+		//Arrange
+		req := tax.ProjectionRequest{
+			TaxRequest: tax.TaxRequest{TotalIncome: 500000.0},
+			Years:      1,
+		}
+		configs := []tax.TaxYearConfig{
+			{
+				TaxYear:    2570,
+				Deductions: DefaultDeductions,
+				Brackets: []tax.TaxBracket{
+					{Min: 0, Max: math.MaxFloat64, Rate: 0.1},
+				},
+			},
+		}
+
+		//Act
+		got := TaxProjectionCalculator(req, configs)
+
+		//Assert
+		assert.Equal(t, 44000.0, got.Projections[0].Tax)
+	})
+}
+
+func TestTaxSensitivityCalculator(t *testing.T) {
+
+	t.Run("Sweep 200,000.0 to 600,000.0 should return marginal rates and breakpoints", func(t *testing.T) {
+		//Arrange
+		req := tax.SensitivityRequest{
+			MinIncome: 200000.0,
+			MaxIncome: 600000.0,
+			Step:      200000.0,
+		}
+
+		//Act
+		got := TaxSensitivityCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Len(t, got.Points, 3)
+		assert.Equal(t, 0.0, got.Points[0].MarginalRate)
+		assert.Equal(t, 0.1, got.Points[1].MarginalRate)
+		assert.Equal(t, 19000.0, got.Points[1].Tax)
+		assert.Equal(t, 0.15, got.Points[2].MarginalRate)
+		assert.Len(t, got.Breakpoints, 5)
+		assert.Equal(t, 210000.0, got.Breakpoints[1].TotalIncome)
+		assert.Equal(t, 150000.0, got.Breakpoints[1].TaxableIncome)
+	})
+
+	t.Run("Fractional step should still end at MaxIncome", func(t *testing.T) {
+		//Arrange
+		req := tax.SensitivityRequest{
+			MinIncome: 0.0,
+			MaxIncome: 0.3,
+			Step:      0.1,
+		}
+
+		//Act
+		got := TaxSensitivityCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Len(t, got.Points, 4)
+		assert.Equal(t, 0.3, got.Points[3].TotalIncome)
+	})
+}
+
+func TestTaxDiffCalculator(t *testing.T) {
+
+	t.Run("Income, donation and WHT changes should add up to the tax change", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxDiffRequest{
+			Before: tax.TaxRequest{
+				TotalIncome: 500000.0,
+			},
+			After: tax.TaxRequest{
+				TotalIncome: 600000.0,
+				Wht:         5000.0,
+				Allowances: []tax.Allowance{
+					{AllowanceType: "donation", Amount: 50000.0},
+				},
+			},
+		}
+		config := tax.TaxYearConfig{Deductions: DefaultDeductions}
+
+		//Act
+		got := TaxDiffCalculator(req, config, config)
+
+		//Assert
+		assert.Equal(t, 29000.0, got.Tax.Before)
+		assert.Equal(t, 29000.0, got.Tax.After)
+		assert.Equal(t, 50000.0, got.TaxableIncome.Change)
+		assert.Equal(t, []tax.TaxAttribution{
+			{Cause: "totalIncome", TaxEffect: 12000.0},
+			{Cause: "allowance:personal", TaxEffect: 0.0},
+			{Cause: "allowance:donation", TaxEffect: -7000.0},
+			{Cause: "wht", TaxEffect: -5000.0},
+		}, got.Attributions)
+
+		total := 0.0
+		for _, attribution := range got.Attributions {
+			total += attribution.TaxEffect
+		}
+		assert.Equal(t, got.Tax.Change, total)
+	})
+
+	t.Run("Levels should be matched by bounds when the brackets differ", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxDiffRequest{
+			Before: tax.TaxRequest{TotalIncome: 600000.0},
+			After:  tax.TaxRequest{TotalIncome: 600000.0},
+		}
+		beforeConfig := tax.TaxYearConfig{Deductions: DefaultDeductions}
+		afterConfig := tax.TaxYearConfig{Deductions: DefaultDeductions, Brackets: []tax.TaxBracket{
+			{Min: 0, Max: 150000, Rate: 0},
+			{Min: 150000, Max: 500000, Rate: 0.10},
+			{Min: 500000, Max: math.MaxFloat64, Rate: 0.20},
+		}}
+
+		//Act
+		got := TaxDiffCalculator(req, beforeConfig, afterConfig)
+
+		//Assert
+		assert.Equal(t, []tax.TaxLevelChange{
+			{Level: "0 - 150,000", Tax: tax.AmountChange{}},
+			{Level: "150,001 - 500,000", Tax: tax.AmountChange{Before: 35000.0, After: 35000.0}},
+			{Level: "500,001 - 1,000,000", Tax: tax.AmountChange{Before: 6000.0, After: 0.0, Change: -6000.0}},
+			{Level: "500,001 ขึ้นไป", Tax: tax.AmountChange{Before: 0.0, After: 8000.0, Change: 8000.0}},
+			{Level: "1,000,001 - 2,000,000", Tax: tax.AmountChange{}},
+			{Level: "2,000,001 ขึ้นไป", Tax: tax.AmountChange{}},
+		}, got.TaxLevels)
+	})
+
+	t.Run("Rounding should get its own attribution", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxDiffRequest{
+			Before: tax.TaxRequest{TotalIncome: 500000.0},
+			After:  tax.TaxRequest{TotalIncome: 600000.55},
+		}
+		config := tax.TaxYearConfig{Deductions: DefaultDeductions, RoundingPolicy: RoundingFloorBaht}
+
+		//Act
+		got := TaxDiffCalculator(req, config, config)
+
+		//Assert
+		assert.Equal(t, 12000.0, got.Tax.Change)
+		rounding := got.Attributions[len(got.Attributions)-1]
+		assert.Equal(t, "rounding", rounding.Cause)
+		assert.InDelta(t, -0.0825, rounding.TaxEffect, 0.0000001)
+
+		total := 0.0
+		for _, attribution := range got.Attributions {
+			total += attribution.TaxEffect
+		}
+		assert.InDelta(t, got.Tax.Change, total, 0.0000001)
+	})
+}
+
+func TestTaxCalculatorExplanation(t *testing.T) {
+
+	t.Run("English explanation should mention capped donation and refund", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Wht:         25000.0,
+			Allowances: []tax.Allowance{
+				{AllowanceType: "donation", Amount: 200000.0},
+			},
+			Language: "en",
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, "Your total income is 500,000.00 baht."+
+			" Personal allowance: claimed 60,000.00, allowed 60,000.00."+
+			" Donation: claimed 200,000.00, allowed 100,000.00 because it is capped at the maximum."+
+			" Taxable income after deductions is 340,000.00 baht."+
+			" Bracket 0 - 150,000 is taxed at 0%: 0.00 baht."+
+			" Bracket 150,001 - 500,000 is taxed at 10%: 19,000.00 baht."+
+			" Total tax is 19,000.00 baht."+
+			" Tax already paid through WHT and provisional tax is 25,000.00 baht."+
+			" Because tax already paid is more than the tax due, you get a refund of 6,000.00 baht.", got.Explanation)
+	})
+
+	t.Run("Thai explanation should state tax payable", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Language:    "th",
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Contains(t, got.Explanation, "ภาษีที่ต้องชำระเพิ่ม 29,000.00 บาท")
+	})
+
+	t.Run("No language should return no explanation", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Empty(t, got.Explanation)
+	})
+}
+
+func TestLevelLabel(t *testing.T) {
+
+	t.Run("Default locale should keep the existing labels", func(t *testing.T) {
+		//Act
+		got := TaxCalculator(tax.TaxRequest{TotalIncome: 500000.0}, 60000.0, 50000.0)
+
+		//Assert
+		assert.Equal(t, "0 - 150,000", got.TaxLevels[0].Level)
+		assert.Equal(t, "150,001 - 500,000", got.TaxLevels[1].Level)
+		assert.Equal(t, "2,000,001 ขึ้นไป", got.TaxLevels[4].Level)
+		assert.Equal(t, 150000.0, got.TaxLevels[1].Min)
+		assert.Equal(t, 500000.0, got.TaxLevels[1].Max)
+		assert.Equal(t, 0.1, got.TaxLevels[1].Rate)
+		assert.Equal(t, 0.0, got.TaxLevels[4].Max)
+	})
+
+	t.Run("English locale should return and above", func(t *testing.T) {
+		//Act
+		got := LevelLabel(tax.TaxBracket{Min: 2000000, Max: math.MaxFloat64, Rate: 0.3}, LocaleEnglish)
+
+		//Assert
+		assert.Equal(t, "2,000,001 and above", got)
+	})
+
+	t.Run("Thai numerals locale should return Thai digits", func(t *testing.T) {
+		//Act
+		got := LevelLabel(tax.TaxBracket{Min: 150000, Max: 500000, Rate: 0.1}, LocaleThaiNumerals)
+
+		//Assert
+		assert.Equal(t, "๑๕๐,๐๐๑ - ๕๐๐,๐๐๐", got)
+	})
+}
+
+func TestTaxCalculatorRoundingPolicy(t *testing.T) {
+
+	t.Run("No policy should report none and keep satang", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 210005.55,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, RoundingNone, got.RoundingPolicy)
+		assert.InDelta(t, 0.555, got.Tax, 0.0000001)
+	})
+
+	t.Run("Half up to the satang should round 0.555 to 0.56", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome:    210005.55,
+			RoundingPolicy: RoundingHalfUpSatang,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, RoundingHalfUpSatang, got.RoundingPolicy)
+		assert.Equal(t, 0.56, got.Tax)
+		assert.Equal(t, 0.56, got.TaxLevels[1].Tax)
+	})
+
+	t.Run("Net tax of the levels should add up to the rounded tax", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome:    812345.67,
+			Wht:            1000.555,
+			RoundingPolicy: RoundingHalfUpSatang,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		netTax := 0.0
+		for _, level := range got.TaxLevels {
+			netTax += level.NetTax
+		}
+		assert.Equal(t, 71851.3, got.Tax)
+		assert.InDelta(t, got.Tax, netTax, 0.0000001)
+	})
+
+	t.Run("Floor to the baht should apply to levels and refund", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 500009.99,
+			Wht:         30000.50,
+		}
+		config := tax.TaxYearConfig{Deductions: DefaultDeductions, RoundingPolicy: RoundingFloorBaht}
+
+		//Act
+		got := TaxCalculatorWithConfig(req, config)
+
+		//Assert
+		assert.Equal(t, RoundingFloorBaht, got.RoundingPolicy)
+		assert.Equal(t, 29000.0, got.TaxLevels[1].Tax)
+		assert.Equal(t, 1000.0, got.TaxRefund)
+	})
+}
+
+func TestTaxCalculatorCreditPerLevel(t *testing.T) {
+
+	t.Run("WHT should be credited from the highest bracket down", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 700000.0,
+			Wht:         20000.0,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 36000.0, got.Tax)
+		assert.Equal(t, 21000.0, got.TaxLevels[2].Tax)
+		assert.Equal(t, 20000.0, got.TaxLevels[2].Credit)
+		assert.Equal(t, 1000.0, got.TaxLevels[2].NetTax)
+		assert.Equal(t, 0.0, got.TaxLevels[1].Credit)
+		assert.Equal(t, 35000.0, got.TaxLevels[1].NetTax)
+
+		netTax := 0.0
+		for _, level := range got.TaxLevels {
+			netTax += level.NetTax
+		}
+		assert.Equal(t, got.Tax, netTax)
+	})
+
+	t.Run("Refund should be reported on the marginal bracket", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 500000.0,
+			Wht:         30000.0,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 1000.0, got.TaxRefund)
+		assert.Equal(t, 29000.0, got.TaxLevels[1].Credit)
+		assert.Equal(t, 0.0, got.TaxLevels[1].NetTax)
+		assert.Equal(t, 1000.0, got.TaxLevels[1].TaxRefund)
+		assert.Equal(t, 0.0, got.TaxLevels[2].TaxRefund)
+	})
+
+	t.Run("Refund without taxable income should be reported on the first bracket", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxRequest{
+			TotalIncome: 100000.0,
+			Wht:         5000.0,
+		}
+
+		//Act
+		got := TaxCalculatorWithDeductions(req, DefaultDeductions)
+
+		//Assert
+		assert.Equal(t, 5000.0, got.TaxLevels[0].TaxRefund)
+	})
+}
+
+func TestCorporateTaxCalculator(t *testing.T) {
+
+	t.Run("SME net profit 1,000,000.0 should return Tax 105,000.0", func(t *testing.T) {
+		//Arrange
+		req := tax.CorporateTaxRequest{
+			NetProfit:     1000000.0,
+			Revenue:       10000000.0,
+			PaidUpCapital: 1000000.0,
+		}
+
+		//Act
+		got := CorporateTaxCalculator(req, DefaultCorporateTaxConfig)
+
+		//Assert
+		assert.True(t, got.SME)
+		assert.Equal(t, 105000.0, got.Tax)
+		assert.Len(t, got.TaxLevels, 3)
+	})
+
+	t.Run("Rounding policy should apply to corporate tax", func(t *testing.T) {
+		//Arrange
+		req := tax.CorporateTaxRequest{
+			NetProfit:     1000000.55,
+			Revenue:       10000000.0,
+			PaidUpCapital: 1000000.0,
+		}
+		config := DefaultCorporateTaxConfig
+		config.RoundingPolicy = RoundingFloorBaht
+
+		//Act
+		got := CorporateTaxCalculator(req, config)
+
+		//Assert
+		assert.Equal(t, 105000.0, got.Tax)
+		assert.Equal(t, RoundingFloorBaht, got.RoundingPolicy)
+	})
+
+	t.Run("Requested rounding policy should override the default for corporate tax", func(t *testing.T) {
+		//Arrange
+		req := tax.CorporateTaxRequest{
+			NetProfit:      1000000.55,
+			Revenue:        10000000.0,
+			PaidUpCapital:  1000000.0,
+			RoundingPolicy: RoundingHalfUpSatang,
+		}
+		config := DefaultCorporateTaxConfig
+		config.RoundingPolicy = RoundingFloorBaht
+
+		//Act
+		got := CorporateTaxCalculator(req, config)
+
+		//Assert
+		assert.Equal(t, 105000.08, got.Tax)
+		assert.Equal(t, RoundingHalfUpSatang, got.RoundingPolicy)
+	})
+
+	t.Run("Company above the revenue threshold should return flat 20%", func(t *testing.T) {
+		//Arrange
+		req := tax.CorporateTaxRequest{
+			NetProfit:     1000000.0,
+			Revenue:       50000000.0,
+			PaidUpCapital: 1000000.0,
+			Wht:           250000.0,
+		}
+
+		//Act
+		got := CorporateTaxCalculator(req, DefaultCorporateTaxConfig)
+
+		//Assert
+		assert.False(t, got.SME)
+		assert.Equal(t, 0.0, got.Tax)
+		assert.Equal(t, 50000.0, got.TaxRefund)
+		assert.Equal(t, "0 ขึ้นไป", got.TaxLevels[0].Level)
+	})
+}
+
+func TestTaxCSVCalculator(t *testing.T) {
+
+	t.Run("Row income 500,000.0 k-receipt 50,000.0 should return Tax 24,000.0 and keep columns", func(t *testing.T) {
+		//Arrange
+		req := tax.TaxCSVRequest{
+			Line:        3,
+			TotalIncome: 500000.0,
+			Allowances:  []tax.Allowance{{AllowanceType: "k-receipt", Amount: 50000.0}},
+			Columns:     map[string]string{"employee": "A001"},
+		}
+
+		//Act
+		got := TaxCSVCalculator(req, tax.TaxYearConfig{Deductions: DefaultDeductions})
+
+		//Assert
+		assert.Equal(t, 24000.0, got.Tax)
+		assert.Equal(t, 3, got.Line)
+		assert.Equal(t, map[string]string{"employee": "A001"}, got.Columns)
+		assert.Len(t, got.TaxLevels, 5)
+	})
+}
+
+func batchRequests(n int) []tax.TaxCSVRequest {
+	reqs := make([]tax.TaxCSVRequest, n)
+	for i := range reqs {
+		reqs[i] = tax.TaxCSVRequest{
+			TotalIncome: float64(100000 + i*10),
+			Wht:         float64(i % 5000),
+			Allowances:  []tax.Allowance{{AllowanceType: "donation", Amount: float64(i % 200000)}},
+		}
+	}
+	return reqs
+}
+
+func TestTaxCSVBatchCalculator(t *testing.T) {
+	config := tax.TaxYearConfig{Deductions: DefaultDeductions}
+
+	t.Run("Parallel results should match sequential results in input order", func(t *testing.T) {
+		//Arrange
+		reqs := batchRequests(1000)
+
+		//Act
+		got, err := TaxCSVBatchCalculator(context.Background(), reqs, config, 4)
+
+		//Assert
+		assert.NoError(t, err)
+		assert.Len(t, got, len(reqs))
+		for i, req := range reqs {
+			assert.Equal(t, TaxCSVCalculator(req, config), got[i])
+		}
+	})
+
+	t.Run("Cancelled context should stop the batch", func(t *testing.T) {
+		//Arrange
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		//Act
+		got, err := TaxCSVBatchCalculator(ctx, batchRequests(100000), config, 1)
+
+		//Assert
+		assert.Equal(t, context.Canceled, err)
+		assert.Nil(t, got)
+	})
+}
+
+func BenchmarkTaxCSVBatchCalculator(b *testing.B) {
+	config := tax.TaxYearConfig{Deductions: DefaultDeductions}
+	reqs := batchRequests(100000)
+
+	workerCounts := []int{1}
+	if cpus := runtime.NumCPU(); cpus > 1 {
+		workerCounts = append(workerCounts, cpus)
+	}
+
+	for _, workers := range workerCounts {
+		workers := workers
+		b.Run(fmt.Sprintf("100k rows/%d workers", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := TaxCSVBatchCalculator(context.Background(), reqs, config, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(reqs)*b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
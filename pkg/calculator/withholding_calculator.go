@@ -0,0 +1,17 @@
+package calculator
+
+import "github.com/fnk2077/assessment-tax/tax"
+
+// WithholdingCalculator works out the tax to withhold on a single payment at
+// the rate configured for its payment type.
+func WithholdingCalculator(req tax.WithholdingRequest, rate float64) tax.WithholdingResponse {
+	wht := req.Amount * rate
+
+	return tax.WithholdingResponse{
+		PaymentType: req.PaymentType,
+		Amount:      req.Amount,
+		Rate:        rate,
+		Wht:         wht,
+		NetPayment:  req.Amount - wht,
+	}
+}
@@ -0,0 +1,468 @@
+// Package xlsx reads and writes the plain cell values of Excel workbooks. It
+// covers what batch uploads need: text and numbers, no styles or formulas.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MaxColumns is the number of columns of an Excel sheet, A to XFD. Cell
+// references past it are rejected.
+const MaxColumns = 16384
+
+var (
+	ErrSheetNotFound = errors.New("sheet not found")
+	ErrTooLarge      = errors.New("workbook exceeds the size limit")
+)
+
+// Row is a non-empty row of a sheet. Number is the 1-based row number shown
+// by Excel and Cells holds the text of each column, empty where blank.
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// Sheet is a worksheet to write. Each cell is a string, written as text, or
+// a float64, written as a number.
+type Sheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+type workbookXML struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationshipsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type richTextXML struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richTextXML) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type sharedStringsXML struct {
+	Items []richTextXML `xml:"si"`
+}
+
+type rowXML struct {
+	R     int `xml:"r,attr"`
+	Cells []struct {
+		R  string      `xml:"r,attr"`
+		T  string      `xml:"t,attr"`
+		V  string      `xml:"v"`
+		Is richTextXML `xml:"is"`
+	} `xml:"c"`
+}
+
+// SheetReader reads the rows of a worksheet one at a time, so a sheet is
+// never held in memory as a whole.
+type SheetReader struct {
+	sheet         io.ReadCloser
+	decoder       *xml.Decoder
+	sharedStrings []richTextXML
+	number        int
+}
+
+// OpenSheet opens the named sheet, or the first sheet when name is empty.
+// At most maxBytes are decompressed from the workbook in total, so a small
+// zip bomb fails with ErrTooLarge instead of exhausting memory; 0 means no
+// limit. The reader must be closed after use.
+func OpenSheet(r io.ReaderAt, size int64, name string, maxBytes int64) (*SheetReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	budget := &byteBudget{remaining: maxBytes, unlimited: maxBytes <= 0}
+
+	var workbook workbookXML
+	if err := decodeFile(files, "xl/workbook.xml", budget, &workbook); err != nil {
+		return nil, err
+	}
+	var rels relationshipsXML
+	if err := decodeFile(files, "xl/_rels/workbook.xml.rels", budget, &rels); err != nil {
+		return nil, err
+	}
+
+	sheetID := ""
+	for _, sheet := range workbook.Sheets {
+		if name == "" || sheet.Name == name {
+			sheetID = sheet.ID
+			break
+		}
+	}
+	if sheetID == "" {
+		return nil, ErrSheetNotFound
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == sheetID {
+			sheetPath = rel.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, ErrSheetNotFound
+	}
+
+	var sharedStrings sharedStringsXML
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeFile(files, "xl/sharedStrings.xml", budget, &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := openFile(files, sheetPath, budget)
+	if err != nil {
+		return nil, err
+	}
+	return &SheetReader{
+		sheet:         sheet,
+		decoder:       xml.NewDecoder(budget.reader(sheet)),
+		sharedStrings: sharedStrings.Items,
+	}, nil
+}
+
+// Next returns the next row holding a value. It returns io.EOF after the
+// last row.
+func (s *SheetReader) Next() (Row, error) {
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return Row{}, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row rowXML
+		if err := s.decoder.DecodeElement(&row, &start); err != nil {
+			return Row{}, err
+		}
+		s.number++
+		if row.R > 0 {
+			s.number = row.R
+		}
+
+		cells, err := s.cells(row)
+		if err != nil {
+			return Row{}, err
+		}
+		for _, value := range cells {
+			if value != "" {
+				return Row{Number: s.number, Cells: cells}, nil
+			}
+		}
+	}
+}
+
+func (s *SheetReader) cells(row rowXML) ([]string, error) {
+	var cells []string
+	for _, cell := range row.Cells {
+		column := len(cells)
+		if cell.R != "" {
+			var err error
+			column, err = columnIndex(cell.R)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if column >= MaxColumns {
+			return nil, fmt.Errorf("row %d has more than %d columns", s.number, MaxColumns)
+		}
+
+		value := cell.V
+		switch cell.T {
+		case "s":
+			i, err := strconv.Atoi(cell.V)
+			if err != nil || i < 0 || i >= len(s.sharedStrings) {
+				return nil, fmt.Errorf("invalid shared string in cell %s", cell.R)
+			}
+			value = s.sharedStrings[i].String()
+		case "inlineStr":
+			value = cell.Is.String()
+		}
+
+		for len(cells) <= column {
+			cells = append(cells, "")
+		}
+		cells[column] = value
+	}
+	return cells, nil
+}
+
+// Close closes the worksheet.
+func (s *SheetReader) Close() error {
+	return s.sheet.Close()
+}
+
+// ReadSheet reads all rows of the named sheet, or of the first sheet when
+// name is empty. Rows without any value are skipped.
+func ReadSheet(r io.ReaderAt, size int64, name string) ([]Row, error) {
+	sheet, err := OpenSheet(r, size, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer sheet.Close()
+
+	var rows []Row
+	for {
+		row, err := sheet.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// byteBudget is the number of decompressed bytes still allowed to be read
+// from a workbook, shared by all of its parts.
+type byteBudget struct {
+	remaining int64
+	unlimited bool
+}
+
+func (b *byteBudget) reader(r io.Reader) io.Reader {
+	if b.unlimited {
+		return r
+	}
+	return &budgetReader{r: r, budget: b}
+}
+
+type budgetReader struct {
+	r      io.Reader
+	budget *byteBudget
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.budget.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > b.budget.remaining+1 {
+		p = p[:b.budget.remaining+1]
+	}
+	n, err := b.r.Read(p)
+	b.budget.remaining -= int64(n)
+	if b.budget.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+func openFile(files map[string]*zip.File, name string, budget *byteBudget) (io.ReadCloser, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	if !budget.unlimited && f.UncompressedSize64 > uint64(budget.remaining) {
+		return nil, ErrTooLarge
+	}
+	return f.Open()
+}
+
+func decodeFile(files map[string]*zip.File, name string, budget *byteBudget, v interface{}) error {
+	rc, err := openFile(files, name, budget)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(budget.reader(rc)).Decode(v)
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12".
+// References past column XFD are rejected.
+func columnIndex(ref string) (int, error) {
+	column := 0
+	for i, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			column = column*26 + int(r-'A') + 1
+			if column > MaxColumns {
+				break
+			}
+			continue
+		}
+		if i == 0 {
+			break
+		}
+		return column - 1, nil
+	}
+	return 0, fmt.Errorf("invalid cell reference %s", ref)
+}
+
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// Write writes a workbook holding sheets in order.
+func Write(w io.Writer, sheets []Sheet) error {
+	writer := NewWriter(w)
+	for _, sheet := range sheets {
+		if err := writer.StartSheet(sheet.Name); err != nil {
+			return err
+		}
+		for _, row := range sheet.Rows {
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Close()
+}
+
+// Writer writes a workbook one row at a time, so a large sheet never has to
+// be held in memory. Sheets are written in the order they are started.
+type Writer struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  io.Writer
+	row    int
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w)}
+}
+
+// StartSheet ends the current sheet, if any, and starts the next one.
+func (w *Writer) StartSheet(name string) error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+	w.sheets = append(w.sheets, name)
+	f, err := w.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)))
+	if err != nil {
+		return err
+	}
+	w.sheet = f
+	w.row = 0
+	_, err = io.WriteString(f, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+// WriteRow appends a row to the current sheet. Each cell is a string,
+// written as text, or a float64, written as a number.
+func (w *Writer) WriteRow(cells []interface{}) error {
+	if w.sheet == nil {
+		return errors.New("no sheet started")
+	}
+	w.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.row)
+	for j, cell := range cells {
+		ref := columnName(j) + strconv.Itoa(w.row)
+		switch value := cell.(type) {
+		case float64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(value, 'f', -1, 64))
+		case string:
+			if value != "" {
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escape(value))
+			}
+		default:
+			return fmt.Errorf("unsupported cell value %T", cell)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+func (w *Writer) endSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(w.sheet, `</sheetData></worksheet>`)
+	w.sheet = nil
+	return err
+}
+
+// Close ends the current sheet and writes the parts that list the sheets.
+func (w *Writer) Close() error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+
+	var contentTypes, workbookSheets, workbookRels strings.Builder
+	for i, name := range w.sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			contentTypes.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			workbookRels.String() + `</Relationships>`},
+	}
+	for _, part := range parts {
+		f, err := w.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	return w.zw.Close()
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndReadSheet(t *testing.T) {
+
+	t.Run("Written workbook should read back by sheet name", func(t *testing.T) {
+		//Arrange
+		var buf bytes.Buffer
+		err := Write(&buf, []Sheet{
+			{Name: "results", Rows: [][]interface{}{
+				{"employee", "totalIncome", "tax"},
+				{"A&B", 500000.0, 29000.0},
+			}},
+			{Name: "errors", Rows: [][]interface{}{
+				{"line", "reason"},
+				{3.0, "", "skipped"},
+			}},
+		})
+		assert.NoError(t, err)
+
+		//Act
+		results, err := ReadSheet(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "")
+		assert.NoError(t, err)
+		errs, err := ReadSheet(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "errors")
+		assert.NoError(t, err)
+
+		//Assert
+		assert.Equal(t, []Row{
+			{Number: 1, Cells: []string{"employee", "totalIncome", "tax"}},
+			{Number: 2, Cells: []string{"A&B", "500000", "29000"}},
+		}, results)
+		assert.Equal(t, []string{"3", "", "skipped"}, errs[1].Cells)
+	})
+
+	t.Run("Unknown sheet name should return ErrSheetNotFound", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, Write(&buf, []Sheet{{Name: "Sheet1"}}))
+
+		_, err := ReadSheet(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "taxes")
+
+		assert.Equal(t, ErrSheetNotFound, err)
+	})
+
+	t.Run("Column index should follow Excel column letters", func(t *testing.T) {
+		for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB2": 27} {
+			got, err := columnIndex(ref)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.Equal(t, ref[:len(columnName(want))], columnName(want))
+		}
+	})
+
+	t.Run("Column past XFD should be rejected", func(t *testing.T) {
+		for _, ref := range []string{"XFE1", "ZZZZZZ1", "ZZZZZZZZZZZZZZ1"} {
+			_, err := columnIndex(ref)
+			assert.Error(t, err, ref)
+		}
+		got, err := columnIndex("XFD1")
+		assert.NoError(t, err)
+		assert.Equal(t, MaxColumns-1, got)
+	})
+
+	t.Run("Crafted cell reference should fail instead of padding the row", func(t *testing.T) {
+		data := workbook(t, `<row r="1"><c r="ZZZZZZ1" t="inlineStr"><is><t>x</t></is></c></row>`)
+
+		_, err := ReadSheet(bytes.NewReader(data), int64(len(data)), "")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Sheet decompressing past the limit should return ErrTooLarge", func(t *testing.T) {
+		rows := strings.Repeat(`<row><c t="inlineStr"><is><t>0</t></is></c></row>`, 20000)
+		data := workbook(t, rows)
+
+		sheet, err := OpenSheet(bytes.NewReader(data), int64(len(data)), "", 64<<10)
+		if err == nil {
+			for err == nil {
+				_, err = sheet.Next()
+			}
+			sheet.Close()
+		}
+
+		assert.Equal(t, ErrTooLarge, err)
+	})
+
+	t.Run("Rows should be read one at a time", func(t *testing.T) {
+		data := workbook(t, `<row><c t="inlineStr"><is><t>a</t></is></c></row><row></row><row><c><v>2</v></c></row>`)
+		sheet, err := OpenSheet(bytes.NewReader(data), int64(len(data)), "", 1<<20)
+		assert.NoError(t, err)
+		defer sheet.Close()
+
+		first, err := sheet.Next()
+		assert.NoError(t, err)
+		second, err := sheet.Next()
+		assert.NoError(t, err)
+		_, err = sheet.Next()
+
+		assert.Equal(t, Row{Number: 1, Cells: []string{"a"}}, first)
+		assert.Equal(t, Row{Number: 3, Cells: []string{"2"}}, second)
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+// workbook returns a single-sheet workbook whose sheetData holds rows.
+func workbook(t *testing.T, rows string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, []Sheet{{Name: "Sheet1"}}))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		w, err := zw.Create(f.Name)
+		assert.NoError(t, err)
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			io.WriteString(w, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+rows+`</sheetData></worksheet>`)
+			continue
+		}
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		io.Copy(w, rc)
+		rc.Close()
+	}
+	assert.NoError(t, zw.Close())
+	return out.Bytes()
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"database/sql"
+	"math"
+
+	"github.com/fnk2077/assessment-tax/pkg/calculator"
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+func (p *Postgres) corporateTaxConfig() (tax.CorporateTaxConfig, error) {
+	config := calculator.DefaultCorporateTaxConfig
+	err := p.Db.QueryRow(`SELECT max_paid_up_capital, max_revenue FROM corporate_sme_thresholds ORDER BY id DESC LIMIT 1`).Scan(
+		&config.MaxSMEPaidUpCapital,
+		&config.MaxSMERevenue,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return tax.CorporateTaxConfig{}, err
+	}
+
+	rows, err := p.Db.Query(`SELECT sme, min_income, max_income, rate FROM corporate_tax_rates ORDER BY sme, min_income`)
+	if err != nil {
+		return tax.CorporateTaxConfig{}, err
+	}
+	defer rows.Close()
+
+	var smeBrackets, generalBrackets []tax.TaxBracket
+	for rows.Next() {
+		var sme bool
+		var bracket tax.TaxBracket
+		var maxIncome sql.NullFloat64
+		if err := rows.Scan(&sme, &bracket.Min, &maxIncome, &bracket.Rate); err != nil {
+			return tax.CorporateTaxConfig{}, err
+		}
+		bracket.Max = math.MaxFloat64
+		if maxIncome.Valid {
+			bracket.Max = maxIncome.Float64
+		}
+		if sme {
+			smeBrackets = append(smeBrackets, bracket)
+		} else {
+			generalBrackets = append(generalBrackets, bracket)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return tax.CorporateTaxConfig{}, err
+	}
+
+	if len(smeBrackets) > 0 {
+		config.SMEBrackets = smeBrackets
+	}
+	if len(generalBrackets) > 0 {
+		config.GeneralBrackets = generalBrackets
+	}
+
+	config.RoundingPolicy, err = p.roundingPolicy()
+	if err != nil {
+		return tax.CorporateTaxConfig{}, err
+	}
+	return config, nil
+}
+
+func (p *Postgres) CorporateTaxCalculate(req tax.CorporateTaxRequest) (tax.CorporateTaxResponse, error) {
+	config, err := p.corporateTaxConfig()
+	if err != nil {
+		return tax.CorporateTaxResponse{}, err
+	}
+
+	return calculator.CorporateTaxCalculator(req, config), nil
+}
@@ -0,0 +1,373 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"github.com/fnk2077/assessment-tax/pkg/calculator"
+	"github.com/fnk2077/assessment-tax/tax"
+	"github.com/lib/pq"
+)
+
+// taxJobChunkSize is how many rows the worker calculates per transaction, so
+// a restart loses at most one chunk of work.
+const taxJobChunkSize = 500
+
+// taxJobLease is how long a running job may go without finishing a chunk
+// before another worker takes it over, its own worker being presumed gone.
+const taxJobLease = 2 * time.Minute
+
+func newTaxJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateTaxJob stores the rows returned by next, until it returns io.EOF, as
+// a queued job. The rows are copied in as they come, so a large upload is
+// neither held in memory nor inserted one round trip at a time. The job keeps
+// the deductions and brackets configured now, so every chunk is calculated
+// with the same settings however long the job waits or runs.
+func (p *Postgres) CreateTaxJob(header []string, encoding string, next func() (tax.TaxJobRow, error)) (tax.TaxJob, error) {
+	id, err := newTaxJobID()
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+	config, err := p.taxYearConfig(0)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO tax_jobs (id, status, header, encoding, config, total_rows) VALUES ($1, $2, $3, $4, $5, 0)`,
+		id, tax.JobQueued, headerJSON, encoding, configJSON)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+
+	totalRows, errorRows, err := copyTaxJobRows(tx, id, next)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE tax_jobs SET total_rows = $2, error_rows = $3 WHERE id = $1`, id, totalRows, errorRows)
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tax.TaxJob{}, err
+	}
+	return p.TaxJob(id)
+}
+
+// copyTaxJobRows copies the rows returned by next into tax_job_rows and
+// counts the rows to calculate and the rejected ones.
+func copyTaxJobRows(tx *sql.Tx, id string, next func() (tax.TaxJobRow, error)) (int, int, error) {
+	stmt, err := tx.Prepare(pq.CopyIn("tax_job_rows", "job_id", "row_number", "record", "request", "row_error"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer stmt.Close()
+
+	totalRows, errorRows := 0, 0
+	for i := 0; ; i++ {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if row.Error != nil {
+			errorRows++
+		} else {
+			totalRows++
+		}
+
+		record, err := json.Marshal(row.Record)
+		if err != nil {
+			return 0, 0, err
+		}
+		request, err := nullJSON(row.Request)
+		if err != nil {
+			return 0, 0, err
+		}
+		rowError, err := nullJSON(row.Error)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := stmt.Exec(id, i, string(record), request, rowError); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if _, err := stmt.Exec(); err != nil {
+		return 0, 0, err
+	}
+	return totalRows, errorRows, nil
+}
+
+// nullJSON encodes v as JSON text, or returns NULL when v is a nil pointer.
+func nullJSON[T any](v *T) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (p *Postgres) TaxJob(id string) (tax.TaxJob, error) {
+	var job tax.TaxJob
+	var headerJSON []byte
+	err := p.Db.QueryRow(`SELECT id, status, total_rows, processed_rows, error_rows, error, created_at, updated_at, header, encoding
+		FROM tax_jobs WHERE id = $1`, id).Scan(
+		&job.ID,
+		&job.Status,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&job.ErrorRows,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&headerJSON,
+		&job.Encoding,
+	)
+	if err == sql.ErrNoRows {
+		return tax.TaxJob{}, tax.ErrNotFound
+	}
+	if err != nil {
+		return tax.TaxJob{}, err
+	}
+	if err := json.Unmarshal(headerJSON, &job.Header); err != nil {
+		return tax.TaxJob{}, err
+	}
+	return job, nil
+}
+
+// TaxJobRows returns up to limit rows of a job in file order, starting at the
+// 0-based row from.
+func (p *Postgres) TaxJobRows(id string, from, limit int) ([]tax.TaxJobRow, error) {
+	rows, err := p.Db.Query(`SELECT record, row_error, result FROM tax_job_rows
+		WHERE job_id = $1 AND row_number >= $2 ORDER BY row_number LIMIT $3`, id, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobRows []tax.TaxJobRow
+	for rows.Next() {
+		var record, rowError, result []byte
+		if err := rows.Scan(&record, &rowError, &result); err != nil {
+			return nil, err
+		}
+
+		var row tax.TaxJobRow
+		if err := json.Unmarshal(record, &row.Record); err != nil {
+			return nil, err
+		}
+		if rowError != nil {
+			if err := json.Unmarshal(rowError, &row.Error); err != nil {
+				return nil, err
+			}
+		}
+		if result != nil {
+			if err := json.Unmarshal(result, &row.Result); err != nil {
+				return nil, err
+			}
+		}
+		jobRows = append(jobRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobRows, nil
+}
+
+// RunTaxJobs calculates queued tax jobs until ctx is done, checking for work
+// every interval. Jobs left running by a worker that stopped making progress
+// are picked up again from the first row without a result.
+func (p *Postgres) RunTaxJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			id, err := p.nextTaxJob()
+			if err != nil {
+				log.Printf("tax job: %v", err)
+				break
+			}
+			if id == "" {
+				break
+			}
+			if err := p.runTaxJob(ctx, id); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("tax job %s: %v", id, err)
+				p.Db.Exec(`UPDATE tax_jobs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1`,
+					id, tax.JobFailed, err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextTaxJob marks the oldest queued job, or running job whose lease has
+// expired, as running and returns its ID, or an empty ID when there is
+// nothing to do. Every finished chunk renews the lease.
+func (p *Postgres) nextTaxJob() (string, error) {
+	var id string
+	err := p.Db.QueryRow(`UPDATE tax_jobs SET status = $1, updated_at = NOW()
+		WHERE id = (SELECT id FROM tax_jobs
+			WHERE status = $2 OR (status = $1 AND updated_at < NOW() - $3 * INTERVAL '1 second')
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING id`, tax.JobRunning, tax.JobQueued, taxJobLease.Seconds()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// taxJobConfig returns the settings a job was created with, or the current
+// ones for a job queued before jobs kept their settings.
+func (p *Postgres) taxJobConfig(id string) (tax.TaxYearConfig, error) {
+	var configJSON []byte
+	if err := p.Db.QueryRow(`SELECT config FROM tax_jobs WHERE id = $1`, id).Scan(&configJSON); err != nil {
+		return tax.TaxYearConfig{}, err
+	}
+	if configJSON == nil {
+		return p.taxYearConfig(0)
+	}
+	var config tax.TaxYearConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return tax.TaxYearConfig{}, err
+	}
+	return config, nil
+}
+
+func (p *Postgres) runTaxJob(ctx context.Context, id string) error {
+	config, err := p.taxJobConfig(id)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		done, err := p.runTaxJobChunk(ctx, id, config)
+		if err != nil {
+			return err
+		}
+		if done {
+			_, err := p.Db.Exec(`UPDATE tax_jobs SET status = $2, updated_at = NOW() WHERE id = $1`, id, tax.JobDone)
+			return err
+		}
+	}
+}
+
+// runTaxJobChunk calculates the next rows of a job without a result with
+// config and reports whether none were left.
+func (p *Postgres) runTaxJobChunk(ctx context.Context, id string, config tax.TaxYearConfig) (bool, error) {
+	rows, err := p.Db.Query(`SELECT row_number, request FROM tax_job_rows
+		WHERE job_id = $1 AND request IS NOT NULL AND result IS NULL
+		ORDER BY row_number LIMIT $2`, id, taxJobChunkSize)
+	if err != nil {
+		return false, err
+	}
+	var rowNumbers []int
+	var reqs []tax.TaxCSVRequest
+	for rows.Next() {
+		var rowNumber int
+		var request []byte
+		if err := rows.Scan(&rowNumber, &request); err != nil {
+			rows.Close()
+			return false, err
+		}
+		var req tax.TaxCSVRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			rows.Close()
+			return false, err
+		}
+		rowNumbers = append(rowNumbers, rowNumber)
+		reqs = append(reqs, req)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(reqs) == 0 {
+		return true, nil
+	}
+
+	taxes, err := calculator.TaxCSVBatchCalculator(ctx, reqs, config, p.BatchWorkers)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// A row already given a result by a worker that took the job over is
+	// left alone, so processed_rows counts every row once.
+	processed := int64(0)
+	for i, detail := range taxes {
+		result, err := json.Marshal(detail)
+		if err != nil {
+			return false, err
+		}
+		res, err := tx.Exec(`UPDATE tax_job_rows SET result = $3 WHERE job_id = $1 AND row_number = $2 AND result IS NULL`,
+			id, rowNumbers[i], result)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		processed += n
+	}
+	_, err = tx.Exec(`UPDATE tax_jobs SET processed_rows = processed_rows + $2, updated_at = NOW() WHERE id = $1`,
+		id, processed)
+	if err != nil {
+		return false, err
+	}
+
+	return false, tx.Commit()
+}
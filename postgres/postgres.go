@@ -12,6 +12,10 @@ import (
 
 type Postgres struct {
 	Db *sql.DB
+
+	// BatchWorkers is how many rows of a batch are calculated at once, one
+	// per CPU when 0.
+	BatchWorkers int
 }
 
 func New() (*Postgres, error) {
@@ -27,9 +31,15 @@ func New() (*Postgres, error) {
 	}
 
 	postgresInstance := &Postgres{Db: db}
-	if err := postgresInstance.MigrateTable("deductions"); err != nil {
-		log.Fatal(err)
-		return nil, err
+	for _, tableName := range []string{"deductions", "withholding_rates", "social_security_rates", "tax_brackets", "settings", "corporate_tax_rates", "corporate_sme_thresholds", "tax_jobs", "tax_job_rows"} {
+		if err := postgresInstance.MigrateTable(tableName); err != nil {
+			log.Fatal(err)
+			return nil, err
+		}
+		if err := postgresInstance.MigrateColumns(tableName); err != nil {
+			log.Fatal(err)
+			return nil, err
+		}
 	}
 
 	return postgresInstance, nil
@@ -60,15 +70,130 @@ func (p *Postgres) MigrateTable(tableName string) error {
 	return nil
 }
 
+// MigrateColumns adds columns introduced after a table was first created, so
+// existing databases pick them up on start.
+func (p *Postgres) MigrateColumns(tableName string) error {
+	query := columnMigrationQuery(tableName)
+	if query == "" {
+		return nil
+	}
+	_, err := p.Db.Exec(query)
+	return err
+}
+
 func migrationQuery(tableName string) string {
 	switch tableName {
 	case "deductions":
 		return `CREATE TABLE IF NOT EXISTS deductions (
             id SERIAL PRIMARY KEY,
             personal FLOAT,
-            max_kreceipt FLOAT
+            max_kreceipt FLOAT,
+            max_home_loan_interest FLOAT NOT NULL DEFAULT 100000.0,
+            max_home_purchase FLOAT NOT NULL DEFAULT 100000.0
         );
         INSERT INTO deductions (personal, max_kreceipt) VALUES (60000.0, 50000.00);`
+	case "withholding_rates":
+		return `CREATE TABLE IF NOT EXISTS withholding_rates (
+            payment_type TEXT PRIMARY KEY,
+            rate FLOAT NOT NULL
+        );
+        INSERT INTO withholding_rates (payment_type, rate) VALUES
+            ('service', 0.03),
+            ('professional-fee', 0.03),
+            ('rent', 0.05),
+            ('transport', 0.01),
+            ('advertising', 0.02),
+            ('prize', 0.05),
+            ('interest', 0.15),
+            ('dividend', 0.10);`
+	case "social_security_rates":
+		return `CREATE TABLE IF NOT EXISTS social_security_rates (
+            tax_year INT PRIMARY KEY,
+            rate FLOAT NOT NULL,
+            min_monthly_wage FLOAT NOT NULL,
+            max_monthly_wage FLOAT NOT NULL
+        );
+        INSERT INTO social_security_rates (tax_year, rate, min_monthly_wage, max_monthly_wage) VALUES (2567, 0.05, 1650.0, 15000.0);`
+	case "tax_brackets":
+		return `CREATE TABLE IF NOT EXISTS tax_brackets (
+            id SERIAL PRIMARY KEY,
+            tax_year INT NOT NULL,
+            min_income FLOAT NOT NULL,
+            max_income FLOAT,
+            rate FLOAT NOT NULL
+        );
+        INSERT INTO tax_brackets (tax_year, min_income, max_income, rate) VALUES
+            (2567, 0, 150000, 0),
+            (2567, 150000, 500000, 0.10),
+            (2567, 500000, 1000000, 0.15),
+            (2567, 1000000, 2000000, 0.20),
+            (2567, 2000000, NULL, 0.30);`
+	case "settings":
+		return `CREATE TABLE IF NOT EXISTS settings (
+            key TEXT PRIMARY KEY,
+            value TEXT NOT NULL
+        );
+        INSERT INTO settings (key, value) VALUES ('rounding_policy', 'none');`
+	case "corporate_tax_rates":
+		return `CREATE TABLE IF NOT EXISTS corporate_tax_rates (
+            id SERIAL PRIMARY KEY,
+            sme BOOLEAN NOT NULL,
+            min_income FLOAT NOT NULL,
+            max_income FLOAT,
+            rate FLOAT NOT NULL
+        );
+        INSERT INTO corporate_tax_rates (sme, min_income, max_income, rate) VALUES
+            (TRUE, 0, 300000, 0),
+            (TRUE, 300000, 3000000, 0.15),
+            (TRUE, 3000000, NULL, 0.20),
+            (FALSE, 0, NULL, 0.20);`
+	case "corporate_sme_thresholds":
+		return `CREATE TABLE IF NOT EXISTS corporate_sme_thresholds (
+            id SERIAL PRIMARY KEY,
+            max_paid_up_capital FLOAT NOT NULL,
+            max_revenue FLOAT NOT NULL
+        );
+        INSERT INTO corporate_sme_thresholds (max_paid_up_capital, max_revenue) VALUES (5000000.0, 30000000.0);`
+	case "tax_jobs":
+		return `CREATE TABLE IF NOT EXISTS tax_jobs (
+            id TEXT PRIMARY KEY,
+            status TEXT NOT NULL,
+            header JSONB NOT NULL,
+            encoding TEXT NOT NULL DEFAULT '',
+            config JSONB,
+            total_rows INT NOT NULL,
+            processed_rows INT NOT NULL DEFAULT 0,
+            error_rows INT NOT NULL DEFAULT 0,
+            error TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+        CREATE INDEX IF NOT EXISTS tax_jobs_status_idx ON tax_jobs (status, created_at);`
+	case "tax_job_rows":
+		return `CREATE TABLE IF NOT EXISTS tax_job_rows (
+            job_id TEXT NOT NULL REFERENCES tax_jobs (id) ON DELETE CASCADE,
+            row_number INT NOT NULL,
+            record JSONB NOT NULL,
+            request JSONB,
+            row_error JSONB,
+            result JSONB,
+            PRIMARY KEY (job_id, row_number)
+        );`
+	default:
+		return ""
+	}
+}
+
+func columnMigrationQuery(tableName string) string {
+	switch tableName {
+	case "deductions":
+		return `ALTER TABLE deductions
+            ADD COLUMN IF NOT EXISTS max_home_loan_interest FLOAT NOT NULL DEFAULT 100000.0,
+            ADD COLUMN IF NOT EXISTS max_home_purchase FLOAT NOT NULL DEFAULT 100000.0;`
+	case "tax_jobs":
+		return `ALTER TABLE tax_jobs
+            ADD COLUMN IF NOT EXISTS encoding TEXT NOT NULL DEFAULT '',
+            ADD COLUMN IF NOT EXISTS config JSONB;`
 	default:
 		return ""
 	}
@@ -1,87 +1,296 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
 	"github.com/fnk2077/assessment-tax/pkg/calculator"
 	"github.com/fnk2077/assessment-tax/tax"
 )
 
+var deductionColumns = map[string]string{
+	"personal":           "personal",
+	"k-receipt":          "max_kreceipt",
+	"home-loan-interest": "max_home_loan_interest",
+	"home-purchase":      "max_home_purchase",
+}
+
 func (p *Postgres) ChangeDeduction(amount float64, deductionType string) error {
+	column, ok := deductionColumns[deductionType]
+	if !ok {
+		return nil
+	}
 
-	if deductionType == "personal" {
-		query := `INSERT INTO deductions (personal, max_kreceipt) 
-          SELECT $1, max_kreceipt FROM deductions ORDER BY id DESC LIMIT 1`
+	// Every change appends a copy of the latest row with only the changed
+	// column replaced, so the deductions table keeps its history.
+	columns := []string{"personal", "max_kreceipt", "max_home_loan_interest", "max_home_purchase"}
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = c
+		if c == column {
+			values[i] = "$1"
+		}
+	}
+	query := fmt.Sprintf(`INSERT INTO deductions (%s)
+		SELECT %s FROM deductions ORDER BY id DESC LIMIT 1`, strings.Join(columns, ", "), strings.Join(values, ", "))
 
-		_, err := p.Db.Exec(query, amount)
-		if err != nil {
-			return err
+	_, err := p.Db.Exec(query, amount)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// deductions loads the latest deduction caps together with the social
+// security rate of taxYear. A zero taxYear, or a year without its own rate,
+// falls back to the latest year configured before it.
+func (p *Postgres) deductions(taxYear int) (tax.Deductions, error) {
+	deductions := calculator.DefaultDeductions
+	err := p.Db.QueryRow(`SELECT personal, max_kreceipt, max_home_loan_interest, max_home_purchase
+		FROM deductions ORDER BY id DESC LIMIT 1`).Scan(
+		&deductions.Personal,
+		&deductions.MaxKReceipt,
+		&deductions.MaxHomeLoanInterest,
+		&deductions.MaxHomePurchase,
+	)
+	if err != nil {
+		return tax.Deductions{}, err
+	}
+
+	err = p.Db.QueryRow(`SELECT rate, min_monthly_wage, max_monthly_wage FROM social_security_rates
+		WHERE $1 = 0 OR tax_year <= $1 ORDER BY tax_year DESC LIMIT 1`, taxYear).Scan(
+		&deductions.SocialSecurityRate,
+		&deductions.MinSocialSecurityWage,
+		&deductions.MaxSocialSecurityWage,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return tax.Deductions{}, err
+	}
+	return deductions, nil
+}
+
+// brackets loads the tax brackets of taxYear, or of the latest configured
+// year when taxYear has none. It returns the year the brackets belong to.
+func (p *Postgres) brackets(taxYear int) (int, []tax.TaxBracket, error) {
+	rows, err := p.Db.Query(`SELECT tax_year, min_income, max_income, rate FROM tax_brackets
+		WHERE tax_year = COALESCE(
+			(SELECT tax_year FROM tax_brackets WHERE tax_year = $1 LIMIT 1),
+			(SELECT MAX(tax_year) FROM tax_brackets))
+		ORDER BY min_income`, taxYear)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var bracketYear int
+	var brackets []tax.TaxBracket
+	for rows.Next() {
+		var bracket tax.TaxBracket
+		var maxIncome sql.NullFloat64
+		if err := rows.Scan(&bracketYear, &bracket.Min, &maxIncome, &bracket.Rate); err != nil {
+			return 0, nil, err
+		}
+		bracket.Max = math.MaxFloat64
+		if maxIncome.Valid {
+			bracket.Max = maxIncome.Float64
 		}
-	} else if deductionType == "k-receipt" {
-		query := `INSERT INTO deductions (max_kreceipt, personal) 
-		SELECT $1, personal FROM deductions ORDER BY id DESC LIMIT 1`
+		brackets = append(brackets, bracket)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(brackets) == 0 {
+		return taxYear, calculator.DefaultBrackets, nil
+	}
+	return bracketYear, brackets, nil
+}
+
+// taxYearConfig loads everything needed to calculate tax for taxYear. A zero
+// taxYear resolves to the latest year with configured brackets.
+func (p *Postgres) taxYearConfig(taxYear int) (tax.TaxYearConfig, error) {
+	bracketYear, brackets, err := p.brackets(taxYear)
+	if err != nil {
+		return tax.TaxYearConfig{}, err
+	}
+	if taxYear == 0 {
+		taxYear = bracketYear
+	}
+
+	deductions, err := p.deductions(taxYear)
+	if err != nil {
+		return tax.TaxYearConfig{}, err
+	}
+
+	roundingPolicy, err := p.roundingPolicy()
+	if err != nil {
+		return tax.TaxYearConfig{}, err
+	}
+
+	return tax.TaxYearConfig{
+		TaxYear:        taxYear,
+		Deductions:     deductions,
+		Brackets:       brackets,
+		RoundingPolicy: roundingPolicy,
+	}, nil
+}
+
+// roundingPolicy returns the default rounding policy, empty when none is set.
+func (p *Postgres) roundingPolicy() (string, error) {
+	var roundingPolicy string
+	err := p.Db.QueryRow(`SELECT value FROM settings WHERE key = 'rounding_policy'`).Scan(&roundingPolicy)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return roundingPolicy, nil
+}
+
+func (p *Postgres) ChangeRoundingPolicy(roundingPolicy string) error {
+	_, err := p.Db.Exec(`INSERT INTO settings (key, value) VALUES ('rounding_policy', $1)
+		ON CONFLICT (key) DO UPDATE SET value = $1`, roundingPolicy)
+	return err
+}
+
+func (p *Postgres) ChangeTaxBrackets(taxYear int, brackets []tax.TaxBracket) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-		_, err := p.Db.Exec(query, amount)
+	if _, err := tx.Exec(`DELETE FROM tax_brackets WHERE tax_year = $1`, taxYear); err != nil {
+		return err
+	}
+	for _, bracket := range brackets {
+		var maxIncome sql.NullFloat64
+		if bracket.Max > 0 && bracket.Max < math.MaxFloat64 {
+			maxIncome = sql.NullFloat64{Float64: bracket.Max, Valid: true}
+		}
+		_, err := tx.Exec(`INSERT INTO tax_brackets (tax_year, min_income, max_income, rate) VALUES ($1, $2, $3, $4)`,
+			taxYear, bracket.Min, maxIncome, bracket.Rate)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+
+	return tx.Commit()
+}
+
+func (p *Postgres) ChangeSocialSecurityRate(taxYear int, req tax.SocialSecurityRateRequest) error {
+	_, err := p.Db.Exec(`INSERT INTO social_security_rates (tax_year, rate, min_monthly_wage, max_monthly_wage)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tax_year) DO UPDATE SET rate = $2, min_monthly_wage = $3, max_monthly_wage = $4`,
+		taxYear, req.Rate, req.MinMonthlyWage, req.MaxMonthlyWage)
+	return err
 }
 
 func (p *Postgres) TaxCalculate(req tax.TaxRequest) (tax.TaxResponse, error) {
-	var personalDeduction float64
-	var maxKReceiptDeduction float64
-	err := p.Db.QueryRow(`SELECT personal FROM deductions ORDER BY id DESC LIMIT 1`).Scan(&personalDeduction)
+	config, err := p.taxYearConfig(req.TaxYear)
 	if err != nil {
 		return tax.TaxResponse{}, err
 	}
 
-	err = p.Db.QueryRow(`SELECT max_kreceipt FROM deductions ORDER BY id DESC LIMIT 1`).Scan(&maxKReceiptDeduction)
+	taxResponse := calculator.TaxCalculatorWithConfig(req, config)
+
+	return taxResponse, nil
+}
+
+func (p *Postgres) ProvisionalTaxCalculate(req tax.TaxRequest) (tax.TaxResponse, error) {
+	config, err := p.taxYearConfig(req.TaxYear)
 	if err != nil {
 		return tax.TaxResponse{}, err
 	}
 
-	taxResponse := calculator.TaxCalculator(req, personalDeduction, maxKReceiptDeduction)
+	taxResponse := calculator.ProvisionalTaxCalculator(req, config)
 
 	return taxResponse, nil
 }
 
-func (p *Postgres) TaxCSVCalculate(reqs []tax.TaxCSVRequest) (tax.TaxCSVResponse, error) {
-	var taxCSVResponse tax.TaxCSVResponse
-	var personalDeduction float64
-	var maxKReceiptDeduction float64
-	err := p.Db.QueryRow(`SELECT personal FROM deductions ORDER BY id DESC LIMIT 1`).Scan(&personalDeduction)
+func (p *Postgres) TaxCSVCalculate(ctx context.Context, reqs []tax.TaxCSVRequest) (tax.TaxCSVResponse, error) {
+	config, err := p.taxYearConfig(0)
 	if err != nil {
 		return tax.TaxCSVResponse{}, err
 	}
 
-	err = p.Db.QueryRow(`SELECT max_kreceipt FROM deductions ORDER BY id DESC LIMIT 1`).Scan(&maxKReceiptDeduction)
+	taxes, err := calculator.TaxCSVBatchCalculator(ctx, reqs, config, p.BatchWorkers)
 	if err != nil {
 		return tax.TaxCSVResponse{}, err
 	}
 
-	for _, req := range reqs {
-		var taxCSVResponseDetail tax.TaxCSVResponseDetail
-		taxRequest := tax.TaxRequest{
-			TotalIncome: req.TotalIncome,
-			Wht:         req.Wht,
-			Allowances: []tax.Allowance{
-				{
-					AllowanceType: "donation",
-					Amount:        req.Donation,
-				},
-			},
-		}
-		taxResponse := calculator.TaxCalculator(taxRequest, personalDeduction, maxKReceiptDeduction)
+	return tax.TaxCSVResponse{Taxes: taxes}, nil
+}
+
+// TaxCSVCalculator returns a function calculating batch rows with the
+// deductions and brackets configured now, so that every row of a batch uses
+// the same snapshot however long it takes.
+func (p *Postgres) TaxCSVCalculator() (func(tax.TaxCSVRequest) tax.TaxCSVResponseDetail, error) {
+	config, err := p.taxYearConfig(0)
+	if err != nil {
+		return nil, err
+	}
 
-		taxCSVResponseDetail.TotalIncome = req.TotalIncome
+	return func(req tax.TaxCSVRequest) tax.TaxCSVResponseDetail {
+		return calculator.TaxCSVCalculator(req, config)
+	}, nil
+}
+
+func (p *Postgres) RetirementTaxCalculate(req tax.RetirementTaxRequest) (tax.RetirementTaxResponse, error) {
+	config, err := p.taxYearConfig(0)
+	if err != nil {
+		return tax.RetirementTaxResponse{}, err
+	}
+
+	return calculator.RetirementTaxCalculator(req, config), nil
+}
 
-		if (taxResponse.Tax >= 0) && (taxResponse.TaxRefund == 0.0) {
-			taxCSVResponseDetail.Tax = taxResponse.Tax
-		} else {
-			taxCSVResponseDetail.TaxRefund = taxResponse.TaxRefund
+func (p *Postgres) GrossUpCalculate(req tax.GrossUpRequest) (tax.GrossUpResponse, error) {
+	config, err := p.taxYearConfig(0)
+	if err != nil {
+		return tax.GrossUpResponse{}, err
+	}
+
+	return calculator.GrossUpCalculator(req, config), nil
+}
+
+func (p *Postgres) TaxProjectionCalculate(req tax.ProjectionRequest) (tax.ProjectionResponse, error) {
+	baseConfig, err := p.taxYearConfig(req.TaxRequest.TaxYear)
+	if err != nil {
+		return tax.ProjectionResponse{}, err
+	}
+
+	configs := []tax.TaxYearConfig{baseConfig}
+	for i := 1; i < req.Years; i++ {
+		config, err := p.taxYearConfig(baseConfig.TaxYear + i)
+		if err != nil {
+			return tax.ProjectionResponse{}, err
 		}
-		taxCSVResponse.Taxes = append(taxCSVResponse.Taxes, taxCSVResponseDetail)
+		configs = append(configs, config)
+	}
+
+	return calculator.TaxProjectionCalculator(req, configs), nil
+}
+
+func (p *Postgres) TaxSensitivityCalculate(req tax.SensitivityRequest) (tax.SensitivityResponse, error) {
+	config, err := p.taxYearConfig(req.TaxRequest.TaxYear)
+	if err != nil {
+		return tax.SensitivityResponse{}, err
+	}
+
+	return calculator.TaxSensitivityCalculator(req, config), nil
+}
+
+func (p *Postgres) TaxDiffCalculate(req tax.TaxDiffRequest) (tax.TaxDiffResponse, error) {
+	beforeConfig, err := p.taxYearConfig(req.Before.TaxYear)
+	if err != nil {
+		return tax.TaxDiffResponse{}, err
+	}
+
+	afterConfig, err := p.taxYearConfig(req.After.TaxYear)
+	if err != nil {
+		return tax.TaxDiffResponse{}, err
 	}
 
-	return taxCSVResponse, nil
+	return calculator.TaxDiffCalculator(req, beforeConfig, afterConfig), nil
 }
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/fnk2077/assessment-tax/pkg/calculator"
+	"github.com/fnk2077/assessment-tax/tax"
+)
+
+func (p *Postgres) WithholdingCalculate(req tax.WithholdingRequest) (tax.WithholdingResponse, error) {
+	var rate float64
+	err := p.Db.QueryRow(`SELECT rate FROM withholding_rates WHERE payment_type = $1`, req.PaymentType).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return tax.WithholdingResponse{}, tax.ErrNotFound
+	}
+	if err != nil {
+		return tax.WithholdingResponse{}, err
+	}
+
+	return calculator.WithholdingCalculator(req, rate), nil
+}
+
+func (p *Postgres) WithholdingCSVCalculate(reqs []tax.WithholdingRequest) (tax.WithholdingCSVResponse, error) {
+	var withholdingCSVResponse tax.WithholdingCSVResponse
+	rows, err := p.Db.Query(`SELECT payment_type, rate FROM withholding_rates`)
+	if err != nil {
+		return tax.WithholdingCSVResponse{}, err
+	}
+	defer rows.Close()
+
+	rates := map[string]float64{}
+	for rows.Next() {
+		var paymentType string
+		var rate float64
+		if err := rows.Scan(&paymentType, &rate); err != nil {
+			return tax.WithholdingCSVResponse{}, err
+		}
+		rates[paymentType] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return tax.WithholdingCSVResponse{}, err
+	}
+
+	for _, req := range reqs {
+		rate, ok := rates[req.PaymentType]
+		if !ok {
+			return tax.WithholdingCSVResponse{}, tax.ErrNotFound
+		}
+		withholdingResponse := calculator.WithholdingCalculator(req, rate)
+		withholdingCSVResponse.TotalAmount += withholdingResponse.Amount
+		withholdingCSVResponse.TotalWht += withholdingResponse.Wht
+		withholdingCSVResponse.Withholdings = append(withholdingCSVResponse.Withholdings, withholdingResponse)
+	}
+
+	return withholdingCSVResponse, nil
+}
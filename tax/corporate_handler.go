@@ -0,0 +1,62 @@
+package tax
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CorporateTaxCalculateHandler calculates corporate income tax.
+//
+// @Summary Calculate corporate income tax
+// @Description Calculate corporate income tax on net profit with SME tiered rates when paid-up capital and revenue are within the thresholds
+// @Tags corporate
+// @Accept json
+// @Produce json
+// @Param request body CorporateTaxRequest true "Corporate tax data"
+// @Success 200 {object} CorporateTaxResponse "Returns the corporate tax calculation"
+// @Router /tax/corporate/calculations [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) CorporateTaxCalculateHandler(c echo.Context) error {
+	var req CorporateTaxRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := CorporateTaxRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.CorporateTaxCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func CorporateTaxRequestValidation(req CorporateTaxRequest) error {
+	if req.NetProfit < 0.0 {
+		return errors.New("net profit must be equal or more than 0")
+	}
+	if req.Revenue < 0.0 {
+		return errors.New("revenue must be equal or more than 0")
+	}
+	if req.PaidUpCapital < 0.0 {
+		return errors.New("paid-up capital must be equal or more than 0")
+	}
+	if req.Wht < 0.0 {
+		return errors.New("wht must be more than 0")
+	}
+	if err := localeValidation(req.Locale); err != nil {
+		return err
+	}
+	if err := roundingPolicyValidation(req.RoundingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package tax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorporateTaxCalculate(t *testing.T) {
+
+	t.Run("SME net profit 1,000,000.0 should return tax", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/corporate/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"netProfit": 1000000.0,
+			"revenue": 10000000.0,
+			"paidUpCapital": 1000000.0,
+			"wht": 0.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := CorporateTaxResponse{
+			SME:       true,
+			NetProfit: 1000000.0,
+			Tax:       105000.0,
+		}
+		stubTax := StubTax{
+			corporateTaxCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.CorporateTaxCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got CorporateTaxResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Negative net profit should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/corporate/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"netProfit": -1.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.CorporateTaxCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "net profit must be equal or more than 0", got.Message)
+	})
+
+	t.Run("Unknown rounding policy should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/corporate/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"netProfit": 1000000.0,
+			"roundingPolicy": "banker"
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.CorporateTaxCalculateHandler(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,612 @@
+package tax
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// allowanceTypes are the registered allowance types. Each may appear as an
+// optional column of an uploaded tax CSV and is applied in this order.
+var allowanceTypes = []string{"donation", "k-receipt", "home-loan-interest", "home-purchase", "social-security"}
+
+var csvRequiredColumns = []string{"totalIncome", "wht"}
+
+// csvIDColumns name the columns that identify a row, in order of preference.
+var csvIDColumns = []string{"id", "employeeId"}
+
+// taxCSVColumns maps every column name of header to its index. Columns with
+// a blank name, such as those left by trailing commas, are ignored. It fails
+// when a required column is missing or a column name is repeated.
+func taxCSVColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if isBlankTaxCSVColumn(name) {
+			continue
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %s", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+	return columns, nil
+}
+
+// taxCSVRecord builds a TaxCSVRequest from one CSV record. An allowance
+// column that is missing from the header, blank or 0 claims no allowance,
+// and columns the calculation does not know about are kept in Columns so
+// they can be returned with the result.
+// The returned row error has no Line; the caller knows where the record was.
+func taxCSVRecord(header []string, columns map[string]int, record []string) (TaxCSVRequest, *TaxCSVRowError) {
+	var req TaxCSVRequest
+	var rowErr *TaxCSVRowError
+	req.TotalIncome, rowErr = taxCSVAmount(&req, record, columns, "totalIncome", "total income must be more than 0")
+	if rowErr != nil {
+		return TaxCSVRequest{}, rowErr
+	}
+	req.Wht, rowErr = taxCSVAmount(&req, record, columns, "wht", "wht must be more than 0")
+	if rowErr != nil {
+		return TaxCSVRequest{}, rowErr
+	}
+
+	for _, column := range csvIDColumns {
+		if i, ok := columns[column]; ok {
+			req.ID = record[i]
+			break
+		}
+	}
+	for _, allowanceType := range allowanceTypes {
+		i, ok := columns[allowanceType]
+		if !ok || normalizeAmount(record[i]) == "" {
+			continue
+		}
+		amount, rowErr := taxCSVAmount(&req, record, columns, allowanceType, allowanceType+" amount must be equal or more than 0")
+		if rowErr != nil {
+			return TaxCSVRequest{}, rowErr
+		}
+		// An allowance of 0 would make social security work its amount out
+		// from income, so a 0 column is not claimed at all.
+		if amount == 0 {
+			continue
+		}
+		req.Allowances = append(req.Allowances, Allowance{
+			AllowanceType: allowanceType,
+			Amount:        amount,
+		})
+	}
+
+	for i, name := range header {
+		if isTaxCSVColumn(name) || isBlankTaxCSVColumn(name) {
+			continue
+		}
+		if req.Columns == nil {
+			req.Columns = make(map[string]string)
+		}
+		req.Columns[name] = record[i]
+	}
+
+	return req, nil
+}
+
+// taxCSVAmount parses the non-negative amount in column of record, reporting
+// negativeReason when it is below 0. NaN and infinities are not numbers here,
+// as they cannot be calculated with or encoded as JSON. A value that had to be normalized to be
+// parsed is added to req.Normalizations.
+func taxCSVAmount(req *TaxCSVRequest, record []string, columns map[string]int, column, negativeReason string) (float64, *TaxCSVRowError) {
+	value := record[columns[column]]
+	normalized := normalizeAmount(value)
+	amount, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, &TaxCSVRowError{Column: column, Reason: fmt.Sprintf("%s must be a number", column)}
+	}
+	if amount < 0.0 {
+		return 0, &TaxCSVRowError{Column: column, Reason: negativeReason}
+	}
+	if normalized != value {
+		req.Normalizations = append(req.Normalizations, TaxCSVNormalization{
+			Column:     column,
+			Original:   value,
+			Normalized: normalized,
+		})
+	}
+	return amount, nil
+}
+
+func isTaxCSVColumn(name string) bool {
+	for _, column := range append(csvRequiredColumns, csvIDColumns...) {
+		if name == column {
+			return true
+		}
+	}
+	return isAllowanceType(name)
+}
+
+// isTaxCSVAmountColumn reports whether name is a column holding an amount.
+func isTaxCSVAmountColumn(name string) bool {
+	for _, column := range csvRequiredColumns {
+		if name == column {
+			return true
+		}
+	}
+	return isAllowanceType(name)
+}
+
+func isBlankTaxCSVColumn(name string) bool {
+	return strings.TrimSpace(name) == ""
+}
+
+func isAllowanceType(allowanceType string) bool {
+	for _, t := range allowanceTypes {
+		if allowanceType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// taxCSVRow is one data row of a tax CSV as it was received, with the reason
+// it was not calculated when it is invalid.
+type taxCSVRow struct {
+	line   int
+	record []string
+	err    *TaxCSVRowError
+}
+
+// taxRecordReader reads the records of an uploaded file one at a time.
+type taxRecordReader interface {
+	// Read returns the next record and the line it starts on. It returns
+	// io.EOF after the last record and a *csv.ParseError for a malformed
+	// record.
+	Read() ([]string, int, error)
+}
+
+type csvRecordReader struct {
+	reader *csv.Reader
+}
+
+func (r csvRecordReader) Read() ([]string, int, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return record, parseErr.StartLine, err
+		}
+		return nil, 0, err
+	}
+	line, _ := r.reader.FieldPos(0)
+	return record, line, nil
+}
+
+// taxUpload is an uploaded file split into the rows to calculate and the
+// rows that were rejected, with every row kept in file order.
+type taxUpload struct {
+	encoding string
+	header   []string
+	requests []TaxCSVRequest
+	rows     []taxCSVRow
+	errors   []TaxCSVRowError
+}
+
+// taxRecordScanner maps the records of a reader to TaxCSVRequests one row at
+// a time, so a file can be processed without holding all of it.
+type taxRecordScanner struct {
+	reader  taxRecordReader
+	header  []string
+	columns map[string]int
+}
+
+// newTaxRecordScanner reads and checks the header of reader. The returned
+// error is meant for the client.
+func newTaxRecordScanner(reader taxRecordReader) (*taxRecordScanner, error) {
+	header, _, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("Invalid CSV file: missing header")
+	}
+
+	columns, err := taxCSVColumns(header)
+	if err != nil {
+		return nil, errors.New("Invalid CSV file: incorrect header format")
+	}
+
+	return &taxRecordScanner{reader: reader, header: header, columns: columns}, nil
+}
+
+// Next returns the next row and, when the row is valid, its request. It
+// returns io.EOF after the last row, and an error meant for the client when
+// the file cannot be read any further.
+func (s *taxRecordScanner) Next() (taxCSVRow, TaxCSVRequest, error) {
+	record, line, err := s.reader.Read()
+	if err == io.EOF {
+		return taxCSVRow{}, TaxCSVRequest{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		var tooLarge *uploadTooLargeError
+		if errors.As(err, &tooLarge) {
+			return taxCSVRow{}, TaxCSVRequest{}, tooLarge
+		}
+		if !errors.As(err, &parseErr) {
+			return taxCSVRow{}, TaxCSVRequest{}, errors.New("Invalid CSV file")
+		}
+		return taxCSVRow{line: line, record: record, err: &TaxCSVRowError{Line: line, Reason: parseErr.Err.Error()}}, TaxCSVRequest{}, nil
+	}
+
+	for _, field := range record {
+		if !utf8.ValidString(field) {
+			return taxCSVRow{line: line, record: record, err: &TaxCSVRowError{Line: line, Reason: "row is not valid UTF-8 text"}}, TaxCSVRequest{}, nil
+		}
+	}
+
+	taxCSVRequest, rowErr := taxCSVRecord(s.header, s.columns, record)
+	if rowErr != nil {
+		rowErr.Line = line
+		return taxCSVRow{line: line, record: record, err: rowErr}, TaxCSVRequest{}, nil
+	}
+	taxCSVRequest.Line = line
+	return taxCSVRow{line: line, record: record}, taxCSVRequest, nil
+}
+
+var errTooManyRows = errors.New("too many rows")
+
+// strictRowError fails a whole strict upload because of rowErr. The error is
+// meant for the client.
+func strictRowError(rowErr *TaxCSVRowError) error {
+	if rowErr.Column == "" {
+		return errors.New("Invalid CSV file")
+	}
+	return errors.New(rowErr.Reason)
+}
+
+// readTaxRecords maps every row of scanner to a TaxCSVRequest. Invalid rows
+// are reported in the upload unless strict is set, in which case the first
+// one fails the whole file. It returns errTooManyRows when there are more
+// than maxRows rows; any other error is meant for the client.
+func readTaxRecords(scanner *taxRecordScanner, strict bool, maxRows int) (taxUpload, error) {
+	upload := taxUpload{header: scanner.header}
+
+	for {
+		row, taxCSVRequest, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return taxUpload{}, err
+		}
+		if len(upload.rows) == maxRows {
+			return taxUpload{}, errTooManyRows
+		}
+
+		if row.err != nil {
+			if strict {
+				return taxUpload{}, strictRowError(row.err)
+			}
+			upload.errors = append(upload.errors, *row.err)
+		} else {
+			upload.requests = append(upload.requests, taxCSVRequest)
+		}
+		upload.rows = append(upload.rows, row)
+	}
+
+	return upload, nil
+}
+
+// taxResultRows calls fn for every row of a batch in file order, with the
+// result of the row or nil when it was not calculated. It stops at the first
+// error from fn and may be called more than once.
+type taxResultRows func(fn func(row taxCSVRow, detail *TaxCSVResponseDetail) error) error
+
+// taxResults is the outcome of a batch, read row by row so that it can be
+// written without holding all of it. lines is set when the rows were read
+// from an uploaded file and so have a line number.
+type taxResults struct {
+	header    []string
+	rows      taxResultRows
+	hasErrors bool
+	lines     bool
+	encoding  string
+}
+
+// newTaxResults pairs rows with the results of resp, which hold the valid rows
+// in order.
+func newTaxResults(header []string, rows []taxCSVRow, resp TaxCSVResponse) taxResults {
+	results := taxResults{header: header, hasErrors: len(resp.Errors) > 0, encoding: resp.Encoding}
+	results.rows = func(fn func(row taxCSVRow, detail *TaxCSVResponseDetail) error) error {
+		next := 0
+		for _, row := range rows {
+			var detail *TaxCSVResponseDetail
+			if row.err == nil && next < len(resp.Taxes) {
+				detail = &resp.Taxes[next]
+				next++
+			}
+			if err := fn(row, detail); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return results
+}
+
+// writeTaxResults responds with results in the format the client asked for:
+// CSV with format=csv or Accept: text/csv, an .xlsx workbook with format=xlsx
+// or the .xlsx Accept type, and JSON otherwise.
+func writeTaxResults(c echo.Context, results taxResults) error {
+	withLevels := c.QueryParam("taxLevels") == "true"
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+
+	format := c.QueryParam("format")
+	if format == "" && strings.Contains(accept, "text/csv") {
+		format = "csv"
+	}
+	if format == "" && strings.Contains(accept, xlsxContentType) {
+		format = "xlsx"
+	}
+
+	switch format {
+	case "csv":
+		return writeTaxCSV(c, results, withLevels)
+	case "xlsx":
+		return writeTaxXLSX(c, results, withLevels)
+	default:
+		return writeTaxJSON(c, results, c.QueryParam("breakdown") == "true")
+	}
+}
+
+var errStopRows = errors.New("stop rows")
+
+// taxLevelColumns returns the tax level labels to add as columns, taken from
+// the first result since every row is calculated with the same brackets.
+func taxLevelColumns(rows taxResultRows, withLevels bool) ([]string, error) {
+	if !withLevels {
+		return nil, nil
+	}
+	var levels []string
+	err := rows(func(_ taxCSVRow, detail *TaxCSVResponseDetail) error {
+		if detail == nil {
+			return nil
+		}
+		for _, level := range detail.TaxLevels {
+			levels = append(levels, level.Level)
+		}
+		return errStopRows
+	})
+	if err != nil && err != errStopRows {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// writeTaxJSON writes results as a TaxCSVResponse, one row at a time. The tax
+// levels and allowances of each row are left out unless breakdown is set.
+//
+// Every result is encoded once before the status is sent, so one that cannot
+// be encoded is answered with a 500 rather than cutting the JSON short.
+func writeTaxJSON(c echo.Context, results taxResults, breakdown bool) error {
+	check := json.NewEncoder(io.Discard)
+	err := results.rows(func(_ taxCSVRow, detail *TaxCSVResponseDetail) error {
+		if detail == nil {
+			return nil
+		}
+		return check.Encode(taxJSONSummary(*detail, breakdown))
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	w := c.Response()
+	encoder := json.NewEncoder(w)
+
+	io.WriteString(w, `{"taxes":[`)
+	first := true
+	err = results.rows(func(_ taxCSVRow, detail *TaxCSVResponseDetail) error {
+		if detail == nil {
+			return nil
+		}
+		if !first {
+			io.WriteString(w, ",")
+		}
+		first = false
+		return encoder.Encode(taxJSONSummary(*detail, breakdown))
+	})
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, "]")
+
+	if results.hasErrors {
+		io.WriteString(w, `,"errors":[`)
+		first = true
+		err := results.rows(func(row taxCSVRow, _ *TaxCSVResponseDetail) error {
+			if row.err == nil {
+				return nil
+			}
+			if !first {
+				io.WriteString(w, ",")
+			}
+			first = false
+			return encoder.Encode(row.err)
+		})
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, "]")
+	}
+
+	if results.encoding != "" {
+		io.WriteString(w, `,"encoding":`)
+		if err := encoder.Encode(results.encoding); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// taxJSONSummary is detail as written to JSON results.
+func taxJSONSummary(detail TaxCSVResponseDetail, breakdown bool) TaxCSVResponseDetail {
+	if !breakdown {
+		detail.TaxLevels = nil
+		detail.Allowances = nil
+	}
+	return detail
+}
+
+// writeTaxCSV streams the rows back as CSV with tax and taxRefund appended.
+// When withLevels is set, one column per tax level follows, and when any row
+// is invalid an error column explains why it has no result.
+func writeTaxCSV(c echo.Context, results taxResults, withLevels bool) error {
+	levels, err := taxLevelColumns(results.rows, withLevels)
+	if err != nil {
+		return err
+	}
+
+	writer := startTaxCSV(c, taxCSVResultHeader(results.header, levels, results.hasErrors))
+
+	var out []string
+	err = results.rows(func(row taxCSVRow, detail *TaxCSVResponseDetail) error {
+		out = appendTaxCSVResult(out[:0], len(results.header), row, detail, len(levels), results.hasErrors)
+		return writer.Write(out)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// startTaxCSV sends the response headers and the header row of a CSV result.
+func startTaxCSV(c echo.Context, header []string) *csv.Writer {
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="taxes.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(c.Response())
+	writer.Write(header)
+	return writer
+}
+
+func taxCSVResultHeader(header, levels []string, withError bool) []string {
+	out := append(append([]string{}, header...), "tax", "taxRefund")
+	out = append(out, levels...)
+	if withError {
+		out = append(out, "error")
+	}
+	return out
+}
+
+// appendTaxCSVResult appends the CSV result of row to out: the original
+// record padded to width, then the result of detail, blank when the row has
+// none.
+func appendTaxCSVResult(out []string, width int, row taxCSVRow, detail *TaxCSVResponseDetail, levels int, withError bool) []string {
+	// Malformed rows may have more or fewer fields than the header.
+	for i := 0; i < width; i++ {
+		value := ""
+		if i < len(row.record) {
+			value = row.record[i]
+		}
+		out = append(out, value)
+	}
+
+	if detail != nil {
+		out = append(out, formatCSVAmount(detail.Tax), formatCSVAmount(detail.TaxRefund))
+	} else {
+		out = append(out, "", "")
+	}
+	for i := 0; i < levels; i++ {
+		value := ""
+		if detail != nil && i < len(detail.TaxLevels) {
+			value = formatCSVAmount(detail.TaxLevels[i].Tax)
+		}
+		out = append(out, value)
+	}
+	if withError {
+		reason := ""
+		if row.err != nil {
+			reason = row.err.Reason
+		}
+		out = append(out, reason)
+	}
+	return out
+}
+
+// taxBatchCSV lays out JSON batch requests as CSV rows: the id when any
+// request has one, the required columns, then every allowance type used by
+// any request, then the pass-through columns in name order.
+func taxBatchCSV(reqs []TaxCSVRequest) ([]string, []taxCSVRow) {
+	var header []string
+	hasID := false
+	usedAllowances := make(map[string]bool)
+	extraColumns := make(map[string]bool)
+	for _, req := range reqs {
+		if req.ID != "" {
+			hasID = true
+		}
+		for _, allowance := range req.Allowances {
+			usedAllowances[allowance.AllowanceType] = true
+		}
+		for name := range req.Columns {
+			extraColumns[name] = true
+		}
+	}
+	if hasID {
+		header = append(header, "id")
+	}
+	header = append(header, csvRequiredColumns...)
+	fixedColumns := len(header)
+	for _, allowanceType := range allowanceTypes {
+		if usedAllowances[allowanceType] {
+			header = append(header, allowanceType)
+		}
+	}
+	var extras []string
+	for name := range extraColumns {
+		extras = append(extras, name)
+	}
+	sort.Strings(extras)
+	header = append(header, extras...)
+
+	rows := make([]taxCSVRow, len(reqs))
+	for i, req := range reqs {
+		amounts := make(map[string]float64)
+		for _, allowance := range req.Allowances {
+			amounts[allowance.AllowanceType] += allowance.Amount
+		}
+
+		var record []string
+		if hasID {
+			record = append(record, req.ID)
+		}
+		record = append(record, formatCSVAmount(req.TotalIncome), formatCSVAmount(req.Wht))
+		for _, name := range header[fixedColumns:] {
+			if usedAllowances[name] {
+				record = append(record, formatCSVAmount(amounts[name]))
+			} else {
+				record = append(record, req.Columns[name])
+			}
+		}
+		rows[i] = taxCSVRow{record: record}
+	}
+	return header, rows
+}
+
+func formatCSVAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
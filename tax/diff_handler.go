@@ -0,0 +1,44 @@
+package tax
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TaxDiffHandler explains the difference between two tax calculations.
+//
+// Only two requests can be compared. Comparing two saved calculation IDs,
+// which the request also asked for, is left as a follow-up: calculations are
+// not stored, so there is nothing for an ID to refer to yet.
+//
+// @Summary Explain why tax changed
+// @Description Compare two tax requests and attribute the change in tax to income, each allowance, brackets and WHT
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body TaxDiffRequest true "Before and after tax data"
+// @Success 200 {object} TaxDiffResponse "Returns the structured diff"
+// @Router /tax/calculations/diff [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxDiffHandler(c echo.Context) error {
+	var req TaxDiffRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	if err := TaxRequestValidation(req.Before); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "before: " + err.Error()})
+	}
+	if err := TaxRequestValidation(req.After); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "after: " + err.Error()})
+	}
+
+	resp, err := h.store.TaxDiffCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
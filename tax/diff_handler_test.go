@@ -0,0 +1,79 @@
+package tax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxDiff(t *testing.T) {
+
+	t.Run("Diff between two requests should return attributions", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/diff", io.NopCloser(strings.NewReader(
+			`{
+			"before": {"totalIncome": 500000.0, "wht": 0.0, "allowances": []},
+			"after": {"totalIncome": 600000.0, "wht": 0.0, "allowances": []}
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := TaxDiffResponse{
+			TotalIncome: AmountChange{Before: 500000.0, After: 600000.0, Change: 100000.0},
+			Tax:         AmountChange{Before: 29000.0, After: 41000.0, Change: 12000.0},
+			Attributions: []TaxAttribution{
+				{Cause: "totalIncome", TaxEffect: 12000.0},
+			},
+		}
+		stubTax := StubTax{
+			taxDiffCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.TaxDiffHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got TaxDiffResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Invalid after request should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/diff", io.NopCloser(strings.NewReader(
+			`{
+			"before": {"totalIncome": 500000.0},
+			"after": {"totalIncome": -1.0}
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.TaxDiffHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "after: total income must be more than 0", got.Message)
+	})
+}
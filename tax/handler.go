@@ -1,14 +1,12 @@
 package tax
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
-
-	"reflect"
-
-	"io"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -17,16 +15,35 @@ var ErrNotFound = errors.New("not found")
 
 type Handler struct {
 	store Storer
+
+	maxUploadSize int64
+	maxUploadRows int
 }
 
 type Storer interface {
 	TaxCalculate(TaxRequest) (TaxResponse, error)
-	TaxCSVCalculate([]TaxCSVRequest) (TaxCSVResponse, error)
+	TaxCSVCalculate(context.Context, []TaxCSVRequest) (TaxCSVResponse, error)
+	TaxCSVCalculator() (func(TaxCSVRequest) TaxCSVResponseDetail, error)
 	ChangeDeduction(float64, string) error
+	ChangeSocialSecurityRate(int, SocialSecurityRateRequest) error
+	ChangeTaxBrackets(int, []TaxBracket) error
+	ChangeRoundingPolicy(string) error
+	TaxProjectionCalculate(ProjectionRequest) (ProjectionResponse, error)
+	TaxSensitivityCalculate(SensitivityRequest) (SensitivityResponse, error)
+	TaxDiffCalculate(TaxDiffRequest) (TaxDiffResponse, error)
+	CorporateTaxCalculate(CorporateTaxRequest) (CorporateTaxResponse, error)
+	CreateTaxJob(header []string, encoding string, next func() (TaxJobRow, error)) (TaxJob, error)
+	TaxJob(id string) (TaxJob, error)
+	TaxJobRows(id string, from, limit int) ([]TaxJobRow, error)
+	RetirementTaxCalculate(RetirementTaxRequest) (RetirementTaxResponse, error)
+	ProvisionalTaxCalculate(TaxRequest) (TaxResponse, error)
+	GrossUpCalculate(GrossUpRequest) (GrossUpResponse, error)
+	WithholdingCalculate(WithholdingRequest) (WithholdingResponse, error)
+	WithholdingCSVCalculate([]WithholdingRequest) (WithholdingCSVResponse, error)
 }
 
 func New(db Storer) *Handler {
-	return &Handler{store: db}
+	return &Handler{store: db, maxUploadSize: DefaultMaxUploadSize, maxUploadRows: DefaultMaxUploadRows}
 }
 
 type Err struct {
@@ -41,6 +58,7 @@ type Err struct {
 // @Accept json
 // @Produce json
 // @Param request body TaxRequest true "Tax data"
+// @Param Accept-Language header string false "th or en to include an explanation"
 // @Success 201 {object} TaxResponse "Returns the tax calculation"
 // @Router /tax/calculations [post]
 // @Failure 400 {object} Err "Bad Request"
@@ -55,6 +73,7 @@ func (h *Handler) TaxCalculateHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
+	req.Language = explanationLanguage(c.Request().Header.Get("Accept-Language"))
 
 	resp, err := h.store.TaxCalculate(req)
 	if err != nil {
@@ -64,6 +83,103 @@ func (h *Handler) TaxCalculateHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// ProvisionalTaxCalculateHandler calculates half-year provisional tax.
+//
+// @Summary Calculate half-year provisional tax (PND 94)
+// @Description Calculate provisional tax on January to June income with halved deductions
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body TaxRequest true "Half-year tax data"
+// @Success 200 {object} TaxResponse "Returns the provisional tax calculation"
+// @Router /tax/provisional/calculations [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) ProvisionalTaxCalculateHandler(c echo.Context) error {
+	var req TaxRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := TaxRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if req.ProvisionalTaxPaid != 0.0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "provisional tax paid is only credited on the annual calculation"})
+	}
+	req.Language = explanationLanguage(c.Request().Header.Get("Accept-Language"))
+
+	resp, err := h.store.ProvisionalTaxCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RetirementTaxCalculateHandler calculates separately taxed retirement lump sum.
+//
+// @Summary Calculate tax on a retirement lump sum
+// @Description Calculate tax on a severance or provident fund lump sum taxed separately from other income
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body RetirementTaxRequest true "Retirement lump sum data"
+// @Success 200 {object} RetirementTaxResponse "Returns the retirement tax calculation"
+// @Router /tax/retirement/calculations [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) RetirementTaxCalculateHandler(c echo.Context) error {
+	var req RetirementTaxRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := RetirementTaxRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.RetirementTaxCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GrossUpCalculateHandler calculates gross income for employer-borne tax.
+//
+// @Summary Calculate gross-up for employer-borne tax
+// @Description Iterate from the intended net income to the grossed-up income and tax when the employer pays the tax
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body GrossUpRequest true "Net income data"
+// @Success 200 {object} GrossUpResponse "Returns the gross-up calculation"
+// @Router /tax/gross-up/calculations [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) GrossUpCalculateHandler(c echo.Context) error {
+	var req GrossUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := TaxRequestValidation(TaxRequest{TotalIncome: req.NetIncome, Allowances: req.Allowances})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.GrossUpCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 // ChangeDeductionHandler changes deduction based on the provided data.
 //
 // @Summary Change deduction
@@ -71,7 +187,7 @@ func (h *Handler) TaxCalculateHandler(c echo.Context) error {
 // @Tags tax
 // @Accept json
 // @Produce json
-// @Param type path string true "Type of deduction: personal or k-receipt"
+// @Param type path string true "Type of deduction: personal, k-receipt, home-loan-interest or home-purchase"
 // @Param amount body DeductionRequest true "Amount to be deducted"
 // @Success 200 {object} map[string]float64 "Returns the updated deduction"
 // @Router /admin/deductions/{type} [post]
@@ -106,6 +222,16 @@ func (h *Handler) ChangeDeductionHandler(c echo.Context) error {
 		if deductionRequest.Amount > 100000 {
 			return c.JSON(http.StatusBadRequest, Err{Message: "Amount must not exceed 100,000"})
 		}
+	} else if deductionType == "home-loan-interest" || deductionType == "home-purchase" {
+		if deductionType == "home-loan-interest" {
+			response = map[string]float64{"homeLoanInterest": deductionRequest.Amount}
+		} else {
+			response = map[string]float64{"homePurchase": deductionRequest.Amount}
+		}
+
+		if deductionRequest.Amount <= 0 {
+			return c.JSON(http.StatusBadRequest, Err{Message: "Amount must be more than 0"})
+		}
 	} else {
 		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid deduction type"})
 	}
@@ -115,87 +241,194 @@ func (h *Handler) ChangeDeductionHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// ChangeSocialSecurityRateHandler changes social security rate for a tax year.
+//
+// @Summary Change social security rate
+// @Description Change the social security contribution rate and monthly wage caps for a tax year
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param year path int true "Tax year in Buddhist Era, e.g. 2567"
+// @Param request body SocialSecurityRateRequest true "Rate and monthly wage caps"
+// @Success 200 {object} SocialSecurityRateRequest "Returns the updated rate"
+// @Router /admin/social-security/{year} [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) ChangeSocialSecurityRateHandler(c echo.Context) error {
+	var req SocialSecurityRateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	taxYear, err := strconv.Atoi(c.Param("year"))
+	if err != nil || taxYear <= 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid tax year"})
+	}
+	if req.Rate <= 0 || req.Rate >= 1 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Rate must be between 0 and 1"})
+	}
+	if req.MinMonthlyWage < 0 || req.MaxMonthlyWage < req.MinMonthlyWage {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Max monthly wage must not be less than min monthly wage"})
+	}
+
+	if err := h.store.ChangeSocialSecurityRate(taxYear, req); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, req)
+}
+
+// ChangeTaxBracketsHandler replaces the tax brackets of a tax year.
+//
+// @Summary Change tax brackets
+// @Description Replace the tax brackets used for a tax year. The last bracket must leave max at 0 for no upper limit, so that all income above its min is taxed.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param year path int true "Tax year in Buddhist Era, e.g. 2567"
+// @Param request body []TaxBracket true "Tax brackets ordered by min"
+// @Success 200 {object} []TaxBracket "Returns the updated brackets"
+// @Router /admin/tax-brackets/{year} [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) ChangeTaxBracketsHandler(c echo.Context) error {
+	var brackets []TaxBracket
+	if err := c.Bind(&brackets); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	taxYear, err := strconv.Atoi(c.Param("year"))
+	if err != nil || taxYear <= 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid tax year"})
+	}
+	if err := TaxBracketsValidation(brackets); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	if err := h.store.ChangeTaxBrackets(taxYear, brackets); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, brackets)
+}
+
+// ChangeRoundingPolicyHandler changes the default rounding policy.
+//
+// @Summary Change default rounding policy
+// @Description Change the rounding policy applied when a request does not set one: none, half-up-satang or floor-baht
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body RoundingPolicyRequest true "Rounding policy"
+// @Success 200 {object} RoundingPolicyRequest "Returns the updated rounding policy"
+// @Router /admin/rounding-policy [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) ChangeRoundingPolicyHandler(c echo.Context) error {
+	var req RoundingPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	if req.RoundingPolicy == "" {
+		return c.JSON(http.StatusBadRequest, Err{Message: "rounding policy is required"})
+	}
+	if err := roundingPolicyValidation(req.RoundingPolicy); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	if err := h.store.ChangeRoundingPolicy(req.RoundingPolicy); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, req)
+}
+
 // TaxCVSCalculateHandler calculates tax from CSV file.
 //
 // @Summary Calculate tax from CSV file
-// @Description Calculate tax based on the data provided in a CSV file
+// @Description Calculate tax based on the data provided in a CSV, TSV or semicolon-delimited file, or in the first sheet of an .xlsx workbook. The format is detected from the content, whatever the file name. Columns are matched by header name: totalIncome and wht are required, an id or employeeId column identifies each row, allowance columns (donation, k-receipt, home-loan-interest, home-purchase, social-security) are optional and other columns are returned as is
 // @Tags tax
 // @Accept multipart/form-data
-// @Param taxFile formData file true "CSV file containing tax data"
-// @Success 200 {object} TaxCSVResponse "Returns the calculated tax"
+// @Param taxFile formData file true "CSV or .xlsx file containing tax data"
+// @Param sheet formData string false "Sheet of the .xlsx workbook to read instead of the first one"
+// @Param strict query bool false "Reject the whole file when any row is invalid instead of reporting the invalid rows"
+// @Param stream query bool false "Calculate a CSV file row by row while it is uploaded and stream the results as NDJSON, or as CSV with format=csv"
+// @Param format query string false "csv or xlsx to download the results as a file"
+// @Param taxLevels query bool false "Add one column per tax level to CSV and .xlsx results"
+// @Param breakdown query bool false "Include the tax levels and allowed deductions of each row in JSON results"
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/x-ndjson
+// @Success 200 {object} TaxCSVResponse "Returns the calculated tax and the rows that could not be calculated"
 // @Router /tax/calculations/upload-csv [post]
 // @Failure 400 {object} Err "Bad Request"
+// @Failure 413 {object} Err "Upload too large"
 // @Failure 500 {object} Err "Internal Server Error"
 func (h *Handler) TaxCVSCalculateHandler(c echo.Context) error {
-	var taxCSVRequests []TaxCSVRequest
-
-	file, err := c.FormFile("taxFile")
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file Key"})
+	if c.QueryParam("stream") == "true" {
+		return h.streamTaxCSV(c)
 	}
 
-	src, err := file.Open()
-	if err != nil || file.Filename != "taxes.csv" {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file name or file not found"})
+	upload, err := h.readTaxUpload(c)
+	if err != nil {
+		return uploadErrorJSON(c, err)
 	}
-	defer src.Close()
 
-	reader := csv.NewReader(src)
-
-	header, err := reader.Read()
+	taxCSVResponse, err := h.store.TaxCSVCalculate(c.Request().Context(), upload.requests)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file: missing header"})
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
 	}
+	taxCSVResponse.Errors = upload.errors
+	taxCSVResponse.Encoding = upload.encoding
 
-	expectedHeader := []string{"totalIncome", "wht", "donation"}
-	if !reflect.DeepEqual(header, expectedHeader) {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file: incorrect header format"})
-	}
+	results := newTaxResults(upload.header, upload.rows, taxCSVResponse)
+	results.lines = true
+	return writeTaxResults(c, results)
+}
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file"})
-		}
+// TaxBatchCalculateHandler calculates tax for a batch of JSON rows.
+//
+// @Summary Calculate tax for a batch
+// @Description Calculate tax for many rows shaped like the rows of an uploaded CSV. Results are returned as JSON, as CSV with Accept: text/csv or format=csv, or as .xlsx with format=xlsx
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param request body TaxBatchRequest true "Rows to calculate"
+// @Param format query string false "csv or xlsx to download the results as a file"
+// @Param taxLevels query bool false "Add one column per tax level to CSV and .xlsx results"
+// @Param breakdown query bool false "Include the tax levels and allowed deductions of each row in JSON results"
+// @Success 200 {object} TaxCSVResponse "Returns the calculated tax"
+// @Router /tax/calculations/batch [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxBatchCalculateHandler(c echo.Context) error {
+	var req TaxBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
 
-		totalIncome, err := strconv.ParseFloat(record[0], 64)
-		if err != nil {
-			return err
-		}
-		if totalIncome < 0.0 {
-			return c.JSON(http.StatusBadRequest, Err{Message: "total income must be more than 0"})
-		}
-		wht, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			return err
-		}
-		if wht < 0.0 {
-			return c.JSON(http.StatusBadRequest, Err{Message: "wht must be more than 0"})
-		}
-		donation, err := strconv.ParseFloat(record[2], 64)
+	for i, row := range req.Taxes {
+		err := TaxRequestValidation(TaxRequest{
+			TotalIncome: row.TotalIncome,
+			Wht:         row.Wht,
+			Allowances:  row.Allowances,
+		})
 		if err != nil {
-			return err
+			return c.JSON(http.StatusBadRequest, Err{Message: fmt.Sprintf("taxes[%d]: %s", i, err.Error())})
 		}
-		if donation < 0.0 {
-			return c.JSON(http.StatusBadRequest, Err{Message: "donation amount must be equal or more than 0"})
-		}
-
-		taxCSVRequests = append(taxCSVRequests, TaxCSVRequest{
-			TotalIncome: totalIncome,
-			Wht:         wht,
-			Donation:    donation,
-		})
 	}
 
-	taxCSVResponse, err := h.store.TaxCSVCalculate(taxCSVRequests)
+	taxCSVResponse, err := h.store.TaxCSVCalculate(c.Request().Context(), req.Taxes)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
 	}
 
-	return c.JSON(http.StatusOK, taxCSVResponse)
+	header, rows := taxBatchCSV(req.Taxes)
+	return writeTaxResults(c, newTaxResults(header, rows, taxCSVResponse))
 }
 
 func TaxRequestValidation(req TaxRequest) error {
@@ -205,15 +438,178 @@ func TaxRequestValidation(req TaxRequest) error {
 	if req.Wht < 0.0 {
 		return errors.New("wht must be more than 0")
 	}
+	if req.ProvisionalTaxPaid < 0.0 {
+		return errors.New("provisional tax paid must be equal or more than 0")
+	}
+	if err := localeValidation(req.Locale); err != nil {
+		return err
+	}
+	if err := roundingPolicyValidation(req.RoundingPolicy); err != nil {
+		return err
+	}
+
+	for _, source := range req.IncomeSources {
+		if len(source.PayerTaxID) != 13 || strings.Trim(source.PayerTaxID, "0123456789") != "" {
+			return errors.New("payer tax id must be 13 digits")
+		}
+		if source.Income < 0.0 {
+			return errors.New("income source income must be equal or more than 0")
+		}
+		if source.Wht < 0.0 {
+			return errors.New("income source wht must be equal or more than 0")
+		}
+		if source.Wht > source.Income {
+			return fmt.Errorf("wht of payer %s must not exceed its income", source.PayerTaxID)
+		}
+	}
 
+	// Caps apply to an allowance type as a whole, so each type is claimed
+	// once with its total amount.
+	claimed := make(map[string]bool, len(req.Allowances))
 	for _, allowance := range req.Allowances {
 		if allowance.Amount < 0.0 {
 			return errors.New("allowance amount must be equal or more than 0")
 		}
-		if allowance.AllowanceType != "donation" && allowance.AllowanceType != "k-receipt" {
+		if !isAllowanceType(allowance.AllowanceType) {
 			return errors.New("invalid allowance type")
 		}
+		if claimed[allowance.AllowanceType] {
+			return fmt.Errorf("allowance type %s must not be repeated", allowance.AllowanceType)
+		}
+		claimed[allowance.AllowanceType] = true
+		if allowance.CoBorrowers < 0 {
+			return errors.New("co-borrowers must be equal or more than 0")
+		}
+		if allowance.Months < 0 || allowance.Months > 12 {
+			return errors.New("months must be between 0 and 12")
+		}
+		if len(allowance.MonthlyWages) > 12 {
+			return errors.New("monthly wages must not exceed 12 months")
+		}
+		for _, wage := range allowance.MonthlyWages {
+			if wage < 0.0 {
+				return errors.New("monthly wage must be equal or more than 0")
+			}
+		}
 	}
 
 	return nil
 }
+
+func RetirementTaxRequestValidation(req RetirementTaxRequest) error {
+	if req.LumpSum < 0.0 {
+		return errors.New("lump sum must be more than 0")
+	}
+	if req.YearsOfService < 1 {
+		return errors.New("years of service must be at least 1")
+	}
+	if req.Wht < 0.0 {
+		return errors.New("wht must be more than 0")
+	}
+	if err := localeValidation(req.Locale); err != nil {
+		return err
+	}
+	if err := roundingPolicyValidation(req.RoundingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func TaxBracketsValidation(brackets []TaxBracket) error {
+	if len(brackets) == 0 {
+		return errors.New("brackets must not be empty")
+	}
+	if brackets[0].Min != 0 {
+		return errors.New("first bracket must start at 0")
+	}
+	for i, bracket := range brackets {
+		if bracket.Rate < 0 || bracket.Rate >= 1 {
+			return errors.New("bracket rate must be between 0 and 1")
+		}
+		last := i == len(brackets)-1
+		if last && bracket.Max != 0 {
+			return errors.New("last bracket max must be 0 for no upper limit")
+		}
+		if !last && bracket.Max <= bracket.Min {
+			return errors.New("bracket max must be more than min")
+		}
+		if !last && brackets[i+1].Min != bracket.Max {
+			return errors.New("brackets must be contiguous")
+		}
+	}
+
+	return nil
+}
+
+func ProjectionRequestValidation(req ProjectionRequest) error {
+	if err := TaxRequestValidation(req.TaxRequest); err != nil {
+		return err
+	}
+	if req.Years < 1 || req.Years > 50 {
+		return errors.New("years must be between 1 and 50")
+	}
+	if req.IncomeGrowthRate <= -1.0 {
+		return errors.New("income growth rate must be more than -1")
+	}
+	for _, plan := range req.AllowancePlans {
+		if err := TaxRequestValidation(TaxRequest{Allowances: plan.Allowances}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func SensitivityRequestValidation(req SensitivityRequest) error {
+	const maxSensitivityPoints = 10000
+
+	if err := TaxRequestValidation(req.TaxRequest); err != nil {
+		return err
+	}
+	if req.MinIncome < 0.0 {
+		return errors.New("min income must be equal or more than 0")
+	}
+	if req.MaxIncome < req.MinIncome {
+		return errors.New("max income must be equal or more than min income")
+	}
+	if req.Step <= 0.0 {
+		return errors.New("step must be more than 0")
+	}
+	if (req.MaxIncome-req.MinIncome)/req.Step >= maxSensitivityPoints {
+		return fmt.Errorf("range must not produce more than %d points", maxSensitivityPoints)
+	}
+
+	return nil
+}
+
+// explanationLanguage picks the first supported language, "th" or "en", from
+// an Accept-Language header. It returns "" when neither is accepted.
+func explanationLanguage(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		language := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		if language == "th" || language == "en" {
+			return language
+		}
+	}
+	return ""
+}
+
+func localeValidation(locale string) error {
+	switch locale {
+	case "", "th", "en", "th-numerals":
+		return nil
+	default:
+		return errors.New("locale must be th, en or th-numerals")
+	}
+}
+
+func roundingPolicyValidation(roundingPolicy string) error {
+	switch roundingPolicy {
+	case "", "none", "half-up-satang", "floor-baht":
+		return nil
+	default:
+		return errors.New("rounding policy must be none, half-up-satang or floor-baht")
+	}
+}
@@ -0,0 +1,156 @@
+package tax
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// taxJobPageSize is how many rows of a job are loaded at a time while its
+// results are written.
+const taxJobPageSize = 1000
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// CreateTaxJobHandler queues an uploaded file to be calculated in the background.
+//
+// @Summary Create a tax batch job
+// @Description Upload a CSV or .xlsx file with the same rules as /tax/calculations/upload-csv. The rows are stored and calculated in the background; poll /tax/jobs/{id} for progress
+// @Tags jobs
+// @Accept multipart/form-data
+// @Produce json
+// @Param taxFile formData file true "CSV or .xlsx file containing tax data"
+// @Param sheet formData string false "Sheet of the .xlsx workbook to read instead of the first one"
+// @Param strict query bool false "Reject the whole file when any row is invalid instead of reporting the invalid rows"
+// @Success 202 {object} TaxJob "Returns the queued job"
+// @Router /tax/jobs [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 413 {object} Err "Upload too large"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) CreateTaxJobHandler(c echo.Context) error {
+	upload, err := h.openTaxUpload(c)
+	if err != nil {
+		return uploadErrorJSON(c, err)
+	}
+	defer upload.Close()
+
+	// Rows go to the store as they are read, so the file is never held in
+	// memory. A failure to read is kept apart to answer it as the client's.
+	var readErr error
+	n := 0
+	next := func() (TaxJobRow, error) {
+		row, taxCSVRequest, err := upload.scanner.Next()
+		if err == io.EOF {
+			return TaxJobRow{}, io.EOF
+		}
+		if err == nil && n == h.maxUploadRows {
+			err = h.errUploadRows()
+		}
+		if err == nil && row.err != nil && upload.strict {
+			err = strictRowError(row.err)
+		}
+		if err != nil {
+			readErr = err
+			return TaxJobRow{}, err
+		}
+		n++
+
+		jobRow := TaxJobRow{Record: row.record, Error: row.err}
+		if row.err == nil {
+			jobRow.Request = &taxCSVRequest
+		}
+		return jobRow, nil
+	}
+
+	job, err := h.store.CreateTaxJob(upload.scanner.header, upload.encoding, next)
+	if readErr != nil {
+		return uploadErrorJSON(c, readErr)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// TaxJobHandler reports the progress of a tax batch job.
+//
+// @Summary Get a tax batch job
+// @Description Get the status and progress of a tax batch job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} TaxJob "Returns the job"
+// @Router /tax/jobs/{id} [get]
+// @Failure 404 {object} Err "Not Found"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxJobHandler(c echo.Context) error {
+	job, err := h.store.TaxJob(c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		return c.JSON(http.StatusNotFound, Err{Message: "Tax job not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// TaxJobResultsHandler downloads the results of a finished tax batch job.
+//
+// @Summary Get tax batch job results
+// @Description Download the results of a finished tax batch job as JSON, as CSV with Accept: text/csv or format=csv, or as .xlsx with format=xlsx
+// @Tags jobs
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path string true "Job ID"
+// @Param format query string false "csv or xlsx to download the results as a file"
+// @Param taxLevels query bool false "Add one column per tax level to CSV and .xlsx results"
+// @Param breakdown query bool false "Include the tax levels and allowed deductions of each row in JSON results"
+// @Success 200 {object} TaxCSVResponse "Returns the calculated tax"
+// @Router /tax/jobs/{id}/results [get]
+// @Failure 404 {object} Err "Not Found"
+// @Failure 409 {object} Err "Job is not done"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxJobResultsHandler(c echo.Context) error {
+	id := c.Param("id")
+	job, err := h.store.TaxJob(id)
+	if errors.Is(err, ErrNotFound) {
+		return c.JSON(http.StatusNotFound, Err{Message: "Tax job not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+	if job.Status != JobDone {
+		return c.JSON(http.StatusConflict, Err{Message: "Tax job is " + job.Status})
+	}
+
+	results := taxResults{header: job.Header, hasErrors: job.ErrorRows > 0, lines: true, encoding: job.Encoding}
+	results.rows = func(fn func(row taxCSVRow, detail *TaxCSVResponseDetail) error) error {
+		for from := 0; ; {
+			page, err := h.store.TaxJobRows(id, from, taxJobPageSize)
+			if err != nil {
+				return err
+			}
+			for _, row := range page {
+				if err := fn(taxCSVRow{record: row.Record, err: row.Error}, row.Result); err != nil {
+					return err
+				}
+			}
+			if len(page) < taxJobPageSize {
+				return nil
+			}
+			from += len(page)
+		}
+	}
+
+	return writeTaxResults(c, results)
+}
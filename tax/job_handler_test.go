@@ -0,0 +1,199 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTaxJob(t *testing.T) {
+
+	t.Run("Upload should queue the rows and return 202", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht\n500000.0,0.0\nabc,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/jobs", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := TaxJob{ID: "job-1", Status: JobQueued, TotalRows: 1, ErrorRows: 1}
+		stubTax := StubTax{taxJob: expected}
+
+		handler := New(&stubTax)
+		if err := handler.CreateTaxJobHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		var got TaxJob
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, expected, got)
+		assert.Equal(t, []TaxJobRow{
+			{Record: []string{"500000.0", "0.0"}, Request: &TaxCSVRequest{Line: 2, TotalIncome: 500000.0}},
+			{Record: []string{"abc", "0.0"}, Error: &TaxCSVRowError{Line: 3, Column: "totalIncome", Reason: "totalIncome must be a number"}},
+		}, stubTax.taxJobRows)
+	})
+
+	t.Run("Windows-874 upload should keep its encoding on the job", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht,\xaa\xd7\xe8\xcd\n500000.0,0.0,\xca\xc1\xaa\xd2\xc2\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/jobs", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{taxJob: TaxJob{ID: "job-1", Status: JobQueued, TotalRows: 1}}
+		handler := New(&stubTax)
+		if err := handler.CreateTaxJobHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, EncodingWindows874, stubTax.taxJobEncoding)
+	})
+
+	t.Run("Strict upload with an invalid row should return 400", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht\n500000.0,0.0\nabc,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/jobs?strict=true", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.CreateTaxJobHandler(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "totalIncome must be a number", got.Message)
+	})
+}
+
+func TestTaxJob(t *testing.T) {
+
+	t.Run("Unknown job should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/tax/jobs/unknown", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("unknown")
+
+		handler := New(&StubTax{err: ErrNotFound})
+		handler.TaxJobHandler(c)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestTaxJobResults(t *testing.T) {
+
+	t.Run("Running job should return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/tax/jobs/job-1/results", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("job-1")
+
+		handler := New(&StubTax{taxJob: TaxJob{ID: "job-1", Status: JobRunning}})
+		handler.TaxJobResultsHandler(c)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Done job should return results as CSV", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/tax/jobs/job-1/results?format=csv", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("job-1")
+
+		stubTax := StubTax{
+			taxJob: TaxJob{ID: "job-1", Status: JobDone, Header: []string{"totalIncome", "wht"}},
+			taxJobRows: []TaxJobRow{
+				{Record: []string{"500000.0", "0.0"}, Result: &TaxCSVResponseDetail{TotalIncome: 500000.0, Tax: 29000.0}},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxJobResultsHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "totalIncome,wht,tax,taxRefund\n500000.0,0.0,29000,0\n", rec.Body.String())
+	})
+
+	t.Run("Done job should page through its rows for JSON results", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/tax/jobs/job-1/results", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("job-1")
+
+		stubTax := StubTax{taxJob: TaxJob{ID: "job-1", Status: JobDone, ErrorRows: 1, Encoding: EncodingWindows874, Header: []string{"totalIncome", "wht"}}}
+		for i := 0; i < taxJobPageSize+1; i++ {
+			stubTax.taxJobRows = append(stubTax.taxJobRows, TaxJobRow{
+				Record: []string{"500000.0", "0.0"},
+				Result: &TaxCSVResponseDetail{TotalIncome: 500000.0, Tax: 29000.0, TaxLevels: []TaxLevel{{Level: "0 - 150,000"}}},
+			})
+		}
+		stubTax.taxJobRows = append(stubTax.taxJobRows, TaxJobRow{
+			Record: []string{"abc", "0.0"},
+			Error:  &TaxCSVRowError{Line: 1003, Column: "totalIncome", Reason: "totalIncome must be a number"},
+		})
+
+		handler := New(&stubTax)
+		if err := handler.TaxJobResultsHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got TaxCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Len(t, got.Taxes, taxJobPageSize+1)
+		assert.Equal(t, TaxCSVResponseDetail{TotalIncome: 500000.0, Tax: 29000.0}, got.Taxes[taxJobPageSize])
+		assert.Equal(t, []TaxCSVRowError{{Line: 1003, Column: "totalIncome", Reason: "totalIncome must be a number"}}, got.Errors)
+		assert.Equal(t, EncodingWindows874, got.Encoding)
+	})
+}
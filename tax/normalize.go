@@ -0,0 +1,125 @@
+package tax
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const EncodingWindows874 = "windows-874"
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// thousandsSeparated matches a number grouped with commas, such as
+// "1,250,000.00", which Thai Excel exports write for formatted cells.
+var thousandsSeparated = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?$`)
+
+var thaiDigits = strings.NewReplacer(
+	"๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4",
+	"๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9",
+)
+
+// newTaxTextReader prepares a delimited text stream for reading as UTF-8. It
+// drops a UTF-8 byte order mark and decodes text that is not valid UTF-8 as
+// Windows-874, the superset of TIS-620 used by Thai Windows and Excel. It
+// returns the encoding that was converted from, empty for UTF-8.
+//
+// A stream can only be judged by its first bytes; a row further on that is
+// not valid UTF-8 is reported by taxRecordScanner instead of being garbled.
+func newTaxTextReader(r *bufio.Reader) (io.Reader, string) {
+	prefix, _ := r.Peek(sniffSize)
+	if bytes.HasPrefix(prefix, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		return r, ""
+	}
+	if !validUTF8Prefix(prefix) {
+		return charmap.Windows874.NewDecoder().Reader(r), EncodingWindows874
+	}
+	return r, ""
+}
+
+// newTaxFileReader is newTaxTextReader for an uploaded file, which can be
+// read twice: the whole file is checked first, so a Windows-874 file is
+// recognised even when its first lines are plain ASCII.
+func newTaxFileReader(src io.ReadSeeker) (io.Reader, string, error) {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	valid, err := validUTF8Reader(src)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+
+	r := bufio.NewReader(src)
+	if prefix, _ := r.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		return r, "", nil
+	}
+	if !valid {
+		return charmap.Windows874.NewDecoder().Reader(r), EncodingWindows874, nil
+	}
+	return r, "", nil
+}
+
+// validUTF8Prefix reports whether p is valid UTF-8, allowing the last rune to
+// be cut short where the prefix ends.
+func validUTF8Prefix(p []byte) bool {
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(p)
+		}
+		p = p[size:]
+	}
+	return true
+}
+
+// validUTF8Reader reports whether everything read from r is valid UTF-8.
+func validUTF8Reader(r io.Reader) (bool, error) {
+	buf := make([]byte, 64<<10)
+	carry := 0
+	for {
+		n, err := r.Read(buf[carry:])
+		chunk := buf[:carry+n]
+		if err == io.EOF {
+			return utf8.Valid(chunk), nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !validUTF8Prefix(chunk) {
+			return false, nil
+		}
+
+		// Keep a rune cut short by the end of the chunk for the next read.
+		carry = 0
+		for i := len(chunk) - 1; i >= 0 && i >= len(chunk)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(chunk[i]) {
+				if !utf8.FullRune(chunk[i:]) {
+					carry = copy(buf, chunk[i:])
+				}
+				break
+			}
+		}
+	}
+}
+
+// normalizeAmount rewrites an amount as written in Thai spreadsheets into a
+// form strconv.ParseFloat accepts: surrounding spaces are trimmed, Thai
+// digits become ASCII digits and thousands separators are dropped.
+func normalizeAmount(value string) string {
+	normalized := strings.TrimSpace(strings.ReplaceAll(value, "\u00a0", " "))
+	normalized = thaiDigits.Replace(normalized)
+	if thousandsSeparated.MatchString(normalized) {
+		normalized = strings.ReplaceAll(normalized, ",", "")
+	}
+	return normalized
+}
@@ -0,0 +1,137 @@
+package tax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestNormalizeAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"500000.0", "500000.0"},
+		{" 500000 ", "500000"},
+		{"1,250,000.00", "1250000.00"},
+		{"๑๒๓", "123"},
+		{"๑,๒๕๐,๐๐๐", "1250000"},
+		{"1,25,000", "1,25,000"},
+		{"abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeAmount(tt.value))
+		})
+	}
+}
+
+func TestNewTaxTextReader(t *testing.T) {
+
+	t.Run("UTF-8 BOM should be dropped", func(t *testing.T) {
+		r, encoding := newTaxTextReader(bufio.NewReader(strings.NewReader("\xEF\xBB\xBFtotalIncome,wht\n")))
+		got, _ := io.ReadAll(r)
+
+		assert.Equal(t, "", encoding)
+		assert.Equal(t, "totalIncome,wht\n", string(got))
+	})
+
+	t.Run("Windows-874 text should be decoded", func(t *testing.T) {
+		encoded, err := charmap.Windows874.NewEncoder().String("ชื่อ,totalIncome\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, encoding := newTaxTextReader(bufio.NewReader(strings.NewReader(encoded)))
+		got, _ := io.ReadAll(r)
+
+		assert.Equal(t, EncodingWindows874, encoding)
+		assert.Equal(t, "ชื่อ,totalIncome\n", string(got))
+	})
+}
+
+func TestNewTaxFileReader(t *testing.T) {
+
+	t.Run("Windows-874 text after an ASCII first block should be decoded", func(t *testing.T) {
+		thai, err := charmap.Windows874.NewEncoder().String("สมชาย,1.0,0\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := "name,totalIncome,wht\n" + strings.Repeat("A,1.0,0\n", sniffSize) + thai
+
+		r, encoding, err := newTaxFileReader(strings.NewReader(content))
+		assert.NoError(t, err)
+		got, _ := io.ReadAll(r)
+
+		assert.Equal(t, EncodingWindows874, encoding)
+		assert.True(t, strings.HasSuffix(string(got), "สมชาย,1.0,0\n"))
+	})
+
+	t.Run("UTF-8 text split across reads should stay UTF-8", func(t *testing.T) {
+		content := strings.Repeat("ชื่อ,", 40000)
+
+		_, encoding, err := newTaxFileReader(strings.NewReader(content))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", encoding)
+	})
+}
+
+func TestTaxCVSCalculateNormalization(t *testing.T) {
+
+	t.Run("TIS-620 file with Thai digits should be read and reported", func(t *testing.T) {
+		content, err := charmap.Windows874.NewEncoder().String("ชื่อ,totalIncome,wht\nสมชาย,\"๑,๒๕๐,๐๐๐\",0\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "payroll.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte(content))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{}
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, []TaxCSVRequest{
+			{
+				Line:        2,
+				TotalIncome: 1250000.0,
+				Columns:     map[string]string{"ชื่อ": "สมชาย"},
+				Normalizations: []TaxCSVNormalization{
+					{Column: "totalIncome", Original: "๑,๒๕๐,๐๐๐", Normalized: "1250000"},
+				},
+			},
+		}, stubTax.taxCSVRequests)
+
+		var got TaxCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, EncodingWindows874, got.Encoding)
+	})
+}
@@ -0,0 +1,38 @@
+package tax
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TaxProjectionHandler projects tax over several years.
+//
+// @Summary Project tax over several years
+// @Description Project tax year by year from a base request with income growth and planned allowance changes
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body ProjectionRequest true "Projection data"
+// @Success 200 {object} ProjectionResponse "Returns the year-by-year projection"
+// @Router /tax/projections [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxProjectionHandler(c echo.Context) error {
+	var req ProjectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := ProjectionRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.TaxProjectionCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,105 @@
+package tax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxProjection(t *testing.T) {
+
+	t.Run("Projection over 2 years should return year-by-year table", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/projections", io.NopCloser(strings.NewReader(
+			`{
+			"taxRequest": {
+				"totalIncome": 500000.0,
+				"taxYear": 2567
+			},
+			"years": 2,
+			"incomeGrowthRate": 0.1
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := ProjectionResponse{
+			Projections: []ProjectionYear{
+				{TaxYear: 2567, TotalIncome: 500000.0, Tax: 29000.0, EffectiveRate: 0.058, CumulativeTax: 29000.0},
+				{TaxYear: 2568, TotalIncome: 550000.0, Tax: 34000.0, EffectiveRate: 0.0618, CumulativeTax: 63000.0},
+			},
+		}
+		stubTax := StubTax{
+			taxProjectionCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.TaxProjectionHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got ProjectionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Projection with 0 years should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/projections", io.NopCloser(strings.NewReader(
+			`{
+			"taxRequest": {
+				"totalIncome": 500000.0
+			},
+			"years": 0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.TaxProjectionHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "years must be between 1 and 50", got.Message)
+	})
+}
+
+func TestTaxBracketsValidation(t *testing.T) {
+
+	t.Run("Brackets with a gap should return error", func(t *testing.T) {
+		brackets := []TaxBracket{
+			{Min: 0, Max: 150000, Rate: 0},
+			{Min: 200000, Max: 0, Rate: 0.1},
+		}
+
+		assert.EqualError(t, TaxBracketsValidation(brackets), "brackets must be contiguous")
+	})
+
+	t.Run("Last bracket with a max should return error", func(t *testing.T) {
+		brackets := []TaxBracket{
+			{Min: 0, Max: 150000, Rate: 0},
+			{Min: 150000, Max: 3000000, Rate: 0.1},
+		}
+
+		assert.EqualError(t, TaxBracketsValidation(brackets), "last bracket max must be 0 for no upper limit")
+	})
+}
@@ -0,0 +1,38 @@
+package tax
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TaxSensitivityHandler calculates tax over a range of incomes.
+//
+// @Summary Calculate a tax sensitivity curve
+// @Description Sweep total income over a range for one deduction profile and return tax, marginal and effective rates with bracket breakpoints
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body SensitivityRequest true "Income range and deduction profile"
+// @Success 200 {object} SensitivityResponse "Returns the sensitivity curve"
+// @Router /tax/sensitivity [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) TaxSensitivityHandler(c echo.Context) error {
+	var req SensitivityRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := SensitivityRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.TaxSensitivityCalculate(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,84 @@
+package tax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxSensitivity(t *testing.T) {
+
+	t.Run("Sweep 0 to 500,000.0 by 250,000.0 should return 3 points", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/sensitivity", io.NopCloser(strings.NewReader(
+			`{
+			"taxRequest": {
+				"allowances": []
+			},
+			"minIncome": 0.0,
+			"maxIncome": 500000.0,
+			"step": 250000.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := SensitivityResponse{
+			Points: []SensitivityPoint{
+				{TotalIncome: 0.0},
+				{TotalIncome: 250000.0, Tax: 4000.0, MarginalRate: 0.1, EffectiveRate: 0.016},
+				{TotalIncome: 500000.0, Tax: 29000.0, MarginalRate: 0.1, EffectiveRate: 0.058},
+			},
+		}
+		stubTax := StubTax{
+			taxSensitivityCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.TaxSensitivityHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got SensitivityResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Sweep with too many points should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/sensitivity", io.NopCloser(strings.NewReader(
+			`{
+			"minIncome": 0.0,
+			"maxIncome": 10000000.0,
+			"step": 1.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.TaxSensitivityHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "range must not produce more than 10000 points", got.Message)
+	})
+}
@@ -0,0 +1,155 @@
+package tax
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamFlushRows is how many rows are written between flushes of a
+// streamed response.
+const streamFlushRows = 1000
+
+// streamTaxCSV calculates an uploaded CSV while it is being received and
+// writes each result as soon as it is known, as NDJSON or, when asked for,
+// as CSV. Memory use does not grow with the file, so rows are never
+// collected into a TaxCSVResponse.
+func (h *Handler) streamTaxCSV(c echo.Context) error {
+	if c.QueryParam("strict") == "true" {
+		return c.JSON(http.StatusBadRequest, Err{Message: "strict is not supported when streaming"})
+	}
+
+	if err := h.limitUploadBody(c); err != nil {
+		return uploadErrorJSON(c, err)
+	}
+
+	mr, err := c.Request().MultipartReader()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file Key"})
+	}
+	var part *multipart.Part
+	for {
+		part, err = mr.NextPart()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file Key"})
+		}
+		if part.FormName() == "taxFile" {
+			break
+		}
+	}
+
+	src := bufio.NewReaderSize(&limitedReader{r: part, remaining: h.maxUploadSize, err: h.errUploadSize()}, sniffSize)
+	prefix, _ := src.Peek(sniffSize)
+	isXLSX, delimiter := sniffTaxFormat(prefix, part.Header.Get(echo.HeaderContentType))
+	if isXLSX {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Streaming supports delimited text files only"})
+	}
+
+	text, _ := newTaxTextReader(src)
+	scanner, err := newTaxRecordScanner(newCSVRecordReader(text, delimiter))
+	if err != nil {
+		return uploadErrorJSON(c, err)
+	}
+
+	// Every row is calculated with the deductions in force when the upload
+	// started, even if an admin changes them while it runs.
+	calculate, err := h.store.TaxCSVCalculator()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	asCSV := c.QueryParam("format") == "csv" ||
+		(c.QueryParam("format") == "" && strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/csv"))
+
+	var writeRow func(row taxCSVRow, detail *TaxCSVResponseDetail) error
+	flush := c.Response().Flush
+	if asCSV {
+		var levels []string
+		if c.QueryParam("taxLevels") == "true" {
+			for _, level := range calculate(TaxCSVRequest{}).TaxLevels {
+				levels = append(levels, level.Level)
+			}
+		}
+		writer := startTaxCSV(c, taxCSVResultHeader(scanner.header, levels, true))
+		defer writer.Flush()
+		flush = func() {
+			writer.Flush()
+			c.Response().Flush()
+		}
+
+		var out []string
+		writeRow = func(row taxCSVRow, detail *TaxCSVResponseDetail) error {
+			out = appendTaxCSVResult(out[:0], len(scanner.header), row, detail, len(levels), true)
+			return writer.Write(out)
+		}
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+		c.Response().WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(c.Response())
+		breakdown := c.QueryParam("breakdown") == "true"
+
+		writeRow = func(row taxCSVRow, detail *TaxCSVResponseDetail) error {
+			if detail != nil && !breakdown {
+				detail.TaxLevels = nil
+				detail.Allowances = nil
+			}
+			return encoder.Encode(TaxCSVStreamRow{Line: row.line, Result: detail, Error: row.err})
+		}
+	}
+
+	for n := 1; ; n++ {
+		row, taxCSVRequest, err := scanner.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil && n > h.maxUploadRows {
+			err = h.errUploadRows()
+		}
+		if err != nil {
+			// The status has already been sent, so the failure can only
+			// be reported as a last row.
+			return writeRow(taxCSVRow{err: &TaxCSVRowError{Reason: err.Error()}}, nil)
+		}
+
+		var detail *TaxCSVResponseDetail
+		if row.err == nil {
+			result := calculate(taxCSVRequest)
+			detail = &result
+		}
+		if err := writeRow(row, detail); err != nil {
+			return err
+		}
+
+		if n%streamFlushRows == 0 {
+			flush()
+		}
+	}
+}
+
+// limitedReader reads from r until remaining bytes have been read and then
+// fails with err, so an oversized stream is reported instead of truncated.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, l.err
+	}
+	return n, err
+}
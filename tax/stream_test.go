@@ -0,0 +1,99 @@
+package tax
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamTaxCSVRequest(t *testing.T, target, content string) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+	if err != nil {
+		t.Errorf("create form file error: %v", err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestStreamTaxCSV(t *testing.T) {
+	calculate := func(req TaxCSVRequest) TaxCSVResponseDetail {
+		return TaxCSVResponseDetail{
+			TotalIncome: req.TotalIncome,
+			Tax:         req.TotalIncome / 10,
+			Columns:     req.Columns,
+			TaxLevels:   []TaxLevel{{Level: "all", Tax: req.TotalIncome / 10}},
+		}
+	}
+
+	t.Run("Stream should write one NDJSON line per row", func(t *testing.T) {
+		e := echo.New()
+		req := streamTaxCSVRequest(t, "/tax/calculations/upload-csv?stream=true", "totalIncome,wht\n500000.0,0.0\nabc,0.0\n")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{taxCSVCalculator: calculate})
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `{"line":2,"result":{"totalIncome":500000,"tax":50000}}`+"\n"+
+			`{"line":3,"error":{"line":3,"column":"totalIncome","reason":"totalIncome must be a number"}}`+"\n", rec.Body.String())
+	})
+
+	t.Run("Stream row that is not UTF-8 after an ASCII start should fail", func(t *testing.T) {
+		e := echo.New()
+		content := "name,totalIncome,wht\n" + strings.Repeat("A,1.0,0\n", sniffSize/8) + "\xca\xc1\xaa\xd2\xc2,1.0,0\n"
+		req := streamTaxCSVRequest(t, "/tax/calculations/upload-csv?stream=true", content)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{taxCSVCalculator: calculate})
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		assert.Equal(t, `{"line":514,"error":{"line":514,"reason":"row is not valid UTF-8 text"}}`, lines[len(lines)-1])
+	})
+
+	t.Run("Stream should write CSV with format=csv", func(t *testing.T) {
+		e := echo.New()
+		req := streamTaxCSVRequest(t, "/tax/calculations/upload-csv?stream=true&format=csv&taxLevels=true", "employee,totalIncome,wht\nA001,500000.0,0.0\n")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{taxCSVCalculator: calculate})
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "employee,totalIncome,wht,tax,taxRefund,all,error\nA001,500000.0,0.0,50000,0,50000,\n", rec.Body.String())
+	})
+
+	t.Run("Stream with strict should return error", func(t *testing.T) {
+		e := echo.New()
+		req := streamTaxCSVRequest(t, "/tax/calculations/upload-csv?stream=true&strict=true", "totalIncome,wht\n500000.0,0.0\n")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{taxCSVCalculator: calculate})
+		handler.TaxCVSCalculateHandler(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
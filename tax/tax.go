@@ -1,20 +1,94 @@
 package tax
 
+import "time"
+
 type Allowance struct {
+	AllowanceType string    `json:"allowanceType"`
+	Amount        float64   `json:"amount"`
+	CoBorrowers   int       `json:"coBorrowers,omitempty"`
+	MonthlyWages  []float64 `json:"monthlyWages,omitempty"`
+	Months        int       `json:"months,omitempty"`
+}
+
+type AllowanceDetail struct {
 	AllowanceType string  `json:"allowanceType"`
 	Amount        float64 `json:"amount"`
+	Allowed       float64 `json:"allowed"`
 }
 
+// TaxLevel is the tax of one bracket. Tax is before credits. Credits (WHT,
+// income source WHT and provisional tax paid) are spread from the highest
+// bracket down: each bracket absorbs up to its own tax, leaving NetTax. Credit
+// left after every bracket is zero is the refund and is reported as TaxRefund
+// on the bracket the taxable income falls in, or the first bracket when there
+// is no taxable income. Under a rounding policy the credit is spread as the
+// policy rounded it and each NetTax is rounded too, so NetTax and TaxRefund
+// add up to the top-level Tax and TaxRefund.
 type TaxLevel struct {
 	Level     string  `json:"level"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max,omitempty"`
+	Rate      float64 `json:"rate"`
 	Tax       float64 `json:"tax"`
+	Credit    float64 `json:"credit,omitempty"`
+	NetTax    float64 `json:"netTax"`
 	TaxRefund float64 `json:"taxRefund,omitempty"`
 }
 
+type IncomeSource struct {
+	PayerTaxID string  `json:"payerTaxId"`
+	Income     float64 `json:"income"`
+	Wht        float64 `json:"wht"`
+}
+
 type TaxRequest struct {
-	TotalIncome float64     `json:"totalIncome"`
-	Wht         float64     `json:"wht"`
-	Allowances  []Allowance `json:"allowances"`
+	TotalIncome        float64        `json:"totalIncome"`
+	Wht                float64        `json:"wht"`
+	ProvisionalTaxPaid float64        `json:"provisionalTaxPaid,omitempty"`
+	IncomeSources      []IncomeSource `json:"incomeSources,omitempty"`
+	TaxYear            int            `json:"taxYear,omitempty"`
+	Locale             string         `json:"locale,omitempty"`
+	RoundingPolicy     string         `json:"roundingPolicy,omitempty"`
+	Allowances         []Allowance    `json:"allowances"`
+
+	// Language asks for an explanation in "th" or "en". It is set from the
+	// Accept-Language header rather than the request body.
+	Language string `json:"-"`
+}
+
+type Deductions struct {
+	Personal            float64 `json:"personal"`
+	MaxKReceipt         float64 `json:"maxKReceipt"`
+	MaxDonation         float64 `json:"maxDonation"`
+	MaxHomeLoanInterest float64 `json:"maxHomeLoanInterest"`
+	MaxHomePurchase     float64 `json:"maxHomePurchase"`
+
+	SocialSecurityRate    float64 `json:"socialSecurityRate"`
+	MinSocialSecurityWage float64 `json:"minSocialSecurityWage"`
+	MaxSocialSecurityWage float64 `json:"maxSocialSecurityWage"`
+}
+
+type TaxBracket struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Rate float64 `json:"rate"`
+}
+
+type TaxYearConfig struct {
+	TaxYear        int          `json:"taxYear"`
+	Deductions     Deductions   `json:"deductions"`
+	Brackets       []TaxBracket `json:"brackets"`
+	RoundingPolicy string       `json:"roundingPolicy"`
+}
+
+type RoundingPolicyRequest struct {
+	RoundingPolicy string `json:"roundingPolicy"`
+}
+
+type SocialSecurityRateRequest struct {
+	Rate           float64 `json:"rate"`
+	MinMonthlyWage float64 `json:"minMonthlyWage"`
+	MaxMonthlyWage float64 `json:"maxMonthlyWage"`
 }
 
 type DeductionRequest struct {
@@ -22,23 +96,261 @@ type DeductionRequest struct {
 }
 
 type TaxResponse struct {
-	Tax       float64    `json:"tax"`
-	TaxRefund float64    `json:"taxRefund,omitempty"`
-	TaxLevels []TaxLevel `json:"taxLevel"`
+	Tax            float64           `json:"tax"`
+	TaxRefund      float64           `json:"taxRefund,omitempty"`
+	TaxLevels      []TaxLevel        `json:"taxLevel"`
+	IncomeSources  []IncomeSource    `json:"incomeSources,omitempty"`
+	Allowances     []AllowanceDetail `json:"allowances,omitempty"`
+	Explanation    string            `json:"explanation,omitempty"`
+	RoundingPolicy string            `json:"roundingPolicy,omitempty"`
 }
 
+// TaxCSVRequest is one row of a batch. Line is the line of an uploaded file
+// the row was read from, and is returned with its result.
 type TaxCSVRequest struct {
-	TotalIncome float64 `json:"totalIncome"`
-	Wht         float64 `json:"wht"`
-	Donation    float64 `json:"donation"`
+	ID             string                `json:"id,omitempty"`
+	Line           int                   `json:"line,omitempty"`
+	TotalIncome    float64               `json:"totalIncome"`
+	Wht            float64               `json:"wht"`
+	Allowances     []Allowance           `json:"allowances"`
+	Columns        map[string]string     `json:"columns,omitempty"`
+	Normalizations []TaxCSVNormalization `json:"normalizations,omitempty"`
+}
+
+// TaxCSVNormalization reports a value of an uploaded row that was rewritten
+// before it could be read, such as "๑,๒๕๐,๐๐๐" read as "1250000".
+type TaxCSVNormalization struct {
+	Column     string `json:"column"`
+	Original   string `json:"original"`
+	Normalized string `json:"normalized"`
 }
 
+// TaxCSVResponse holds the results of a batch. Encoding names the character
+// set an uploaded file was converted from when it was not UTF-8.
 type TaxCSVResponse struct {
-	Taxes []TaxCSVResponseDetail `json:"taxes"`
+	Taxes    []TaxCSVResponseDetail `json:"taxes"`
+	Errors   []TaxCSVRowError       `json:"errors,omitempty"`
+	Encoding string                 `json:"encoding,omitempty"`
 }
 
+// TaxCSVRowError reports why a row of an uploaded CSV was not calculated.
+// Column is empty when the row itself is malformed.
+type TaxCSVRowError struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// TaxCSVResponseDetail is the result of one batch row, with the line it was
+// read from when it came from an uploaded file. TaxLevels and Allowances are
+// only returned when the client asks for the breakdown.
 type TaxCSVResponseDetail struct {
-	TotalIncome float64 `json:"totalIncome"`
-	Tax         float64 `json:"tax"`
-	TaxRefund   float64 `json:"taxRefund,omitempty"`
+	ID          string            `json:"id,omitempty"`
+	Line        int               `json:"line,omitempty"`
+	TotalIncome float64           `json:"totalIncome"`
+	Tax         float64           `json:"tax"`
+	TaxRefund   float64           `json:"taxRefund,omitempty"`
+	Columns     map[string]string `json:"columns,omitempty"`
+	TaxLevels   []TaxLevel        `json:"taxLevel,omitempty"`
+	Allowances  []AllowanceDetail `json:"allowances,omitempty"`
+
+	Normalizations []TaxCSVNormalization `json:"normalizations,omitempty"`
+}
+
+// TaxCSVStreamRow is one line of a streamed NDJSON result, holding either the
+// result of a row or the reason it was not calculated.
+type TaxCSVStreamRow struct {
+	Line   int                   `json:"line"`
+	Result *TaxCSVResponseDetail `json:"result,omitempty"`
+	Error  *TaxCSVRowError       `json:"error,omitempty"`
+}
+
+type TaxBatchRequest struct {
+	Taxes []TaxCSVRequest `json:"taxes"`
+}
+
+type RetirementTaxRequest struct {
+	LumpSum        float64 `json:"lumpSum"`
+	YearsOfService int     `json:"yearsOfService"`
+	Wht            float64 `json:"wht"`
+	Locale         string  `json:"locale,omitempty"`
+	RoundingPolicy string  `json:"roundingPolicy,omitempty"`
+}
+
+type RetirementTaxResponse struct {
+	LumpSum          float64    `json:"lumpSum"`
+	ServiceDeduction float64    `json:"serviceDeduction"`
+	HalfDeduction    float64    `json:"halfDeduction"`
+	TaxableIncome    float64    `json:"taxableIncome"`
+	Tax              float64    `json:"tax"`
+	TaxRefund        float64    `json:"taxRefund,omitempty"`
+	TaxLevels        []TaxLevel `json:"taxLevel"`
+	RoundingPolicy   string     `json:"roundingPolicy,omitempty"`
+}
+
+type WithholdingRequest struct {
+	PaymentType string  `json:"paymentType"`
+	Amount      float64 `json:"amount"`
+}
+
+type WithholdingResponse struct {
+	PaymentType string  `json:"paymentType"`
+	Amount      float64 `json:"amount"`
+	Rate        float64 `json:"rate"`
+	Wht         float64 `json:"wht"`
+	NetPayment  float64 `json:"netPayment"`
+}
+
+type WithholdingCSVResponse struct {
+	TotalAmount  float64               `json:"totalAmount"`
+	TotalWht     float64               `json:"totalWht"`
+	Withholdings []WithholdingResponse `json:"withholdings"`
+}
+
+type GrossUpRequest struct {
+	NetIncome  float64     `json:"netIncome"`
+	Allowances []Allowance `json:"allowances"`
+}
+
+type GrossUpResponse struct {
+	NetIncome   float64     `json:"netIncome"`
+	GrossIncome float64     `json:"grossIncome"`
+	Iterations  int         `json:"iterations"`
+	Converged   bool        `json:"converged"`
+	TaxResponse TaxResponse `json:"taxResponse"`
+}
+
+type AllowancePlan struct {
+	TaxYear    int         `json:"taxYear"`
+	Allowances []Allowance `json:"allowances"`
+}
+
+type ProjectionRequest struct {
+	TaxRequest       TaxRequest      `json:"taxRequest"`
+	Years            int             `json:"years"`
+	IncomeGrowthRate float64         `json:"incomeGrowthRate"`
+	AllowancePlans   []AllowancePlan `json:"allowancePlans"`
+}
+
+type ProjectionYear struct {
+	TaxYear       int     `json:"taxYear"`
+	TotalIncome   float64 `json:"totalIncome"`
+	Tax           float64 `json:"tax"`
+	EffectiveRate float64 `json:"effectiveRate"`
+	CumulativeTax float64 `json:"cumulativeTax"`
+}
+
+type ProjectionResponse struct {
+	Projections []ProjectionYear `json:"projections"`
+}
+
+type SensitivityRequest struct {
+	TaxRequest TaxRequest `json:"taxRequest"`
+	MinIncome  float64    `json:"minIncome"`
+	MaxIncome  float64    `json:"maxIncome"`
+	Step       float64    `json:"step"`
+}
+
+type SensitivityPoint struct {
+	TotalIncome   float64 `json:"totalIncome"`
+	Tax           float64 `json:"tax"`
+	MarginalRate  float64 `json:"marginalRate"`
+	EffectiveRate float64 `json:"effectiveRate"`
+}
+
+type Breakpoint struct {
+	TotalIncome   float64 `json:"totalIncome"`
+	TaxableIncome float64 `json:"taxableIncome"`
+	Rate          float64 `json:"rate"`
+}
+
+type SensitivityResponse struct {
+	Points      []SensitivityPoint `json:"points"`
+	Breakpoints []Breakpoint       `json:"breakpoints"`
+}
+
+type TaxDiffRequest struct {
+	Before TaxRequest `json:"before"`
+	After  TaxRequest `json:"after"`
+}
+
+type AmountChange struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`
+}
+
+type AllowanceChange struct {
+	AllowanceType string       `json:"allowanceType"`
+	Amount        AmountChange `json:"amount"`
+	Allowed       AmountChange `json:"allowed"`
+}
+
+type TaxLevelChange struct {
+	Level string       `json:"level"`
+	Tax   AmountChange `json:"tax"`
+}
+
+type TaxAttribution struct {
+	Cause     string  `json:"cause"`
+	TaxEffect float64 `json:"taxEffect"`
+}
+
+type TaxDiffResponse struct {
+	TotalIncome   AmountChange      `json:"totalIncome"`
+	Allowances    []AllowanceChange `json:"allowances"`
+	TaxableIncome AmountChange      `json:"taxableIncome"`
+	TaxLevels     []TaxLevelChange  `json:"taxLevels"`
+	Wht           AmountChange      `json:"wht"`
+	Tax           AmountChange      `json:"tax"`
+	Attributions  []TaxAttribution  `json:"attributions"`
+}
+
+type CorporateTaxRequest struct {
+	NetProfit      float64 `json:"netProfit"`
+	Revenue        float64 `json:"revenue"`
+	PaidUpCapital  float64 `json:"paidUpCapital"`
+	Wht            float64 `json:"wht"`
+	Locale         string  `json:"locale,omitempty"`
+	RoundingPolicy string  `json:"roundingPolicy,omitempty"`
+}
+
+type CorporateTaxConfig struct {
+	MaxSMEPaidUpCapital float64      `json:"maxSmePaidUpCapital"`
+	MaxSMERevenue       float64      `json:"maxSmeRevenue"`
+	SMEBrackets         []TaxBracket `json:"smeBrackets"`
+	GeneralBrackets     []TaxBracket `json:"generalBrackets"`
+	RoundingPolicy      string       `json:"roundingPolicy,omitempty"`
+}
+
+type CorporateTaxResponse struct {
+	SME            bool       `json:"sme"`
+	NetProfit      float64    `json:"netProfit"`
+	Tax            float64    `json:"tax"`
+	TaxRefund      float64    `json:"taxRefund,omitempty"`
+	TaxLevels      []TaxLevel `json:"taxLevel"`
+	RoundingPolicy string     `json:"roundingPolicy,omitempty"`
+}
+
+// TaxJobRow is one row of an uploaded file kept with a job. Request is set
+// for a row to calculate and Error for a row that was rejected. Result is set
+// once the row has been calculated.
+type TaxJobRow struct {
+	Record  []string              `json:"record"`
+	Request *TaxCSVRequest        `json:"request,omitempty"`
+	Error   *TaxCSVRowError       `json:"error,omitempty"`
+	Result  *TaxCSVResponseDetail `json:"result,omitempty"`
+}
+
+type TaxJob struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	TotalRows     int       `json:"totalRows"`
+	ProcessedRows int       `json:"processedRows"`
+	ErrorRows     int       `json:"errorRows"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	Encoding      string    `json:"encoding,omitempty"`
+	Header        []string  `json:"-"`
 }
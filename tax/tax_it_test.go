@@ -57,22 +57,37 @@ func TestITTaxCalculate(t *testing.T) {
 			TaxLevels: []TaxLevel{
 				{
 					Level: "0 - 150,000",
+					Min:   0,
+					Max:   150000,
+					Rate:  0,
 					Tax:   0,
 				},
 				{
-					Level: "150,001 - 500,000",
-					Tax:   29000,
+					Level:  "150,001 - 500,000",
+					Min:    150000,
+					Max:    500000,
+					Rate:   0.1,
+					Tax:    29000,
+					NetTax: 29000,
 				},
 				{
 					Level: "500,001 - 1,000,000",
+					Min:   500000,
+					Max:   1000000,
+					Rate:  0.15,
 					Tax:   0,
 				},
 				{
 					Level: "1,000,001 - 2,000,000",
+					Min:   1000000,
+					Max:   2000000,
+					Rate:  0.2,
 					Tax:   0,
 				},
 				{
 					Level: "2,000,001 ขึ้นไป",
+					Min:   2000000,
+					Rate:  0.3,
 					Tax:   0,
 				},
 			},
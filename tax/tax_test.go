@@ -1,24 +1,44 @@
 package tax
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/fnk2077/assessment-tax/pkg/xlsx"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
 type StubTax struct {
-	taxCalculate    TaxResponse
-	taxCSVCalculate TaxCSVResponse
-	changeDeduction error
-	err             error
+	taxCalculate            TaxResponse
+	taxCSVCalculate         TaxCSVResponse
+	retirementTaxCalculate  RetirementTaxResponse
+	grossUpCalculate        GrossUpResponse
+	taxProjectionCalculate  ProjectionResponse
+	taxSensitivityCalculate SensitivityResponse
+	taxDiffCalculate        TaxDiffResponse
+	corporateTaxCalculate   CorporateTaxResponse
+	withholdingCalculate    WithholdingResponse
+	withholdingCSVCalculate WithholdingCSVResponse
+	changeDeduction         error
+	err                     error
+
+	taxCSVRequests []TaxCSVRequest
+
+	taxCSVCalculator func(TaxCSVRequest) TaxCSVResponseDetail
+
+	taxJob         TaxJob
+	taxJobRows     []TaxJobRow
+	taxJobEncoding string
 }
 
 func (s *StubTax) TaxCalculate(TaxRequest) (TaxResponse, error) {
@@ -29,9 +49,89 @@ func (s *StubTax) ChangeDeduction(amount float64, deductionType string) error {
 	return s.changeDeduction
 }
 
-func (s *StubTax) TaxCSVCalculate([]TaxCSVRequest) (TaxCSVResponse, error) {
+func (s *StubTax) ChangeSocialSecurityRate(int, SocialSecurityRateRequest) error {
+	return s.changeDeduction
+}
+
+func (s *StubTax) ChangeTaxBrackets(int, []TaxBracket) error {
+	return s.changeDeduction
+}
+
+func (s *StubTax) ChangeRoundingPolicy(string) error {
+	return s.changeDeduction
+}
+
+func (s *StubTax) TaxProjectionCalculate(ProjectionRequest) (ProjectionResponse, error) {
+	return s.taxProjectionCalculate, s.err
+}
+
+func (s *StubTax) TaxSensitivityCalculate(SensitivityRequest) (SensitivityResponse, error) {
+	return s.taxSensitivityCalculate, s.err
+}
+
+func (s *StubTax) TaxDiffCalculate(TaxDiffRequest) (TaxDiffResponse, error) {
+	return s.taxDiffCalculate, s.err
+}
+
+func (s *StubTax) CorporateTaxCalculate(CorporateTaxRequest) (CorporateTaxResponse, error) {
+	return s.corporateTaxCalculate, s.err
+}
+
+func (s *StubTax) TaxCSVCalculator() (func(TaxCSVRequest) TaxCSVResponseDetail, error) {
+	return s.taxCSVCalculator, s.err
+}
+
+func (s *StubTax) CreateTaxJob(header []string, encoding string, next func() (TaxJobRow, error)) (TaxJob, error) {
+	s.taxJobEncoding = encoding
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return TaxJob{}, err
+		}
+		s.taxJobRows = append(s.taxJobRows, row)
+	}
+	return s.taxJob, s.err
+}
+
+func (s *StubTax) TaxJob(string) (TaxJob, error) {
+	return s.taxJob, s.err
+}
+
+func (s *StubTax) TaxJobRows(_ string, from, limit int) ([]TaxJobRow, error) {
+	if from >= len(s.taxJobRows) {
+		return nil, s.err
+	}
+	return s.taxJobRows[from:min(from+limit, len(s.taxJobRows))], s.err
+}
+
+func (s *StubTax) TaxCSVCalculate(_ context.Context, reqs []TaxCSVRequest) (TaxCSVResponse, error) {
+	s.taxCSVRequests = reqs
 	return s.taxCSVCalculate, s.err
 }
+
+func (s *StubTax) RetirementTaxCalculate(RetirementTaxRequest) (RetirementTaxResponse, error) {
+	return s.retirementTaxCalculate, s.err
+}
+
+func (s *StubTax) ProvisionalTaxCalculate(TaxRequest) (TaxResponse, error) {
+	return s.taxCalculate, s.err
+}
+
+func (s *StubTax) GrossUpCalculate(GrossUpRequest) (GrossUpResponse, error) {
+	return s.grossUpCalculate, s.err
+}
+
+func (s *StubTax) WithholdingCalculate(WithholdingRequest) (WithholdingResponse, error) {
+	return s.withholdingCalculate, s.err
+}
+
+func (s *StubTax) WithholdingCSVCalculate([]WithholdingRequest) (WithholdingCSVResponse, error) {
+	return s.withholdingCSVCalculate, s.err
+}
+
 func TestTaxCalculate(t *testing.T) {
 
 	t.Run("Income 150000.0 should return 0", func(t *testing.T) {
@@ -693,8 +793,308 @@ func TestChangeDeduction(t *testing.T) {
 	})
 }
 
+func TestTaxBatchCalculate(t *testing.T) {
+
+	t.Run("Batch returns CSV with format=csv", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch?format=csv", io.NopCloser(strings.NewReader(
+			`{
+			"taxes": [
+			  {
+				"totalIncome": 500000.0,
+				"wht": 0.0,
+				"allowances": [{"allowanceType": "k-receipt", "amount": 20000.0}],
+				"columns": {"employee": "A001"}
+			  }
+			]
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{TotalIncome: 500000.0, Tax: 27000.0},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxBatchCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "totalIncome,wht,k-receipt,employee,tax,taxRefund\n"+
+			"500000,0,20000,A001,27000,0\n", rec.Body.String())
+	})
+
+	t.Run("Batch with invalid row should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", io.NopCloser(strings.NewReader(
+			`{"taxes": [{"totalIncome": 500000.0}, {"totalIncome": -1.0}]}`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.TaxBatchCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "taxes[1]: total income must be more than 0", got.Message)
+	})
+}
+
 func TestTaxCVSCalculate(t *testing.T) {
 
+	t.Run("Test tax CSV calculate echoes employeeId and breakdown", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("employeeId,department,totalIncome,wht\nA001,HR,500000.0,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?breakdown=true", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		expected := TaxCSVResponse{
+			Taxes: []TaxCSVResponseDetail{
+				{
+					ID:          "A001",
+					TotalIncome: 500000.0,
+					Tax:         29000.0,
+					Columns:     map[string]string{"department": "HR"},
+					TaxLevels:   []TaxLevel{{Level: "150,001 - 500,000", Tax: 29000.0}},
+					Allowances:  []AllowanceDetail{{AllowanceType: "personal", Amount: 60000.0, Allowed: 60000.0}},
+				},
+			},
+		}
+		stubTax := StubTax{
+			taxCSVCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, "A001", stubTax.taxCSVRequests[0].ID)
+		assert.Equal(t, map[string]string{"department": "HR"}, stubTax.taxCSVRequests[0].Columns)
+		var got TaxCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Test tax calculate with xlsx upload returns xlsx results and errors sheets", func(t *testing.T) {
+		var workbook bytes.Buffer
+		err := xlsx.Write(&workbook, []xlsx.Sheet{
+			{Name: "Sheet1"},
+			{Name: "payroll", Rows: [][]interface{}{
+				{"employee", "totalIncome", "wht"},
+				{"A001", 500000.0, 0.0},
+				{"A002", "abc", 0.0},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("write workbook error: %v", err)
+		}
+
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		writer.WriteField("sheet", "payroll")
+		part, err := writer.CreateFormFile("taxFile", "payroll.bin")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write(workbook.Bytes())
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?format=xlsx", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{Line: 2, TotalIncome: 500000.0, Tax: 29000.0},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []TaxCSVRequest{{Line: 2, TotalIncome: 500000.0, Columns: map[string]string{"employee": "A001"}}}, stubTax.taxCSVRequests)
+
+		got := rec.Body.Bytes()
+		results, err := xlsx.ReadSheet(bytes.NewReader(got), int64(len(got)), "results")
+		assert.NoError(t, err)
+		assert.Equal(t, []xlsx.Row{
+			{Number: 1, Cells: []string{"line", "employee", "totalIncome", "wht", "tax", "taxRefund"}},
+			{Number: 2, Cells: []string{"2", "A001", "500000", "0", "29000", "0"}},
+		}, results)
+		rowErrors, err := xlsx.ReadSheet(bytes.NewReader(got), int64(len(got)), "errors")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"3", "totalIncome", "totalIncome must be a number"}, rowErrors[1].Cells)
+
+		// Amount columns are numbers, other columns stay text.
+		archive, err := zip.NewReader(bytes.NewReader(got), int64(len(got)))
+		if err != nil {
+			t.Fatalf("open workbook error: %v", err)
+		}
+		sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+		if err != nil {
+			t.Fatalf("open results sheet error: %v", err)
+		}
+		sheetXML, _ := io.ReadAll(sheet)
+		assert.Contains(t, string(sheetXML), `<c r="B2" t="inlineStr"><is><t>A001</t></is></c>`)
+		assert.Contains(t, string(sheetXML), `<c r="C2"><v>500000</v></c>`)
+		assert.Contains(t, string(sheetXML), `<c r="D2"><v>0</v></c>`)
+	})
+
+	t.Run("Test tax CSV calculate returns CSV when Accept is text/csv", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("employee,totalIncome,wht\nA001,500000.0,0.0\nA002,abc,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?taxLevels=true", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set(echo.HeaderAccept, "text/csv")
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{
+						TotalIncome: 500000.0,
+						Tax:         29000.0,
+						TaxLevels: []TaxLevel{
+							{Level: "0 - 150,000", Tax: 0.0},
+							{Level: "150,001 - 500,000", Tax: 29000.0},
+						},
+					},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "employee,totalIncome,wht,tax,taxRefund,\"0 - 150,000\",\"150,001 - 500,000\",error\n"+
+			"A001,500000.0,0.0,29000,0,0,29000,\n"+
+			"A002,abc,0.0,,,,,totalIncome must be a number\n", rec.Body.String())
+	})
+
+	t.Run("Test tax CSV calculate maps columns by header name", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("employee,k-receipt,wht,totalIncome\nA001,20000.0,0.0,500000.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{}
+
+		handler := New(&stubTax)
+		err = handler.TaxCVSCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		expected := []TaxCSVRequest{
+			{
+				Line:        2,
+				TotalIncome: 500000.0,
+				Wht:         0.0,
+				Allowances: []Allowance{
+					{AllowanceType: "k-receipt", Amount: 20000.0},
+				},
+				Columns: map[string]string{"employee": "A001"},
+			},
+		}
+		assert.Equal(t, expected, stubTax.taxCSVRequests)
+	})
+
+	t.Run("Test tax CSV calculate ignores blank columns and blank or 0 allowances", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht,donation,social-security,,\n600000.0,0.0,,0,,\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{}
+
+		handler := New(&stubTax)
+		err = handler.TaxCVSCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		expected := []TaxCSVRequest{
+			{Line: 2, TotalIncome: 600000.0, Wht: 0.0},
+		}
+		assert.Equal(t, expected, stubTax.taxCSVRequests)
+	})
+
 	t.Run("Test tax CSV calculate with total income 150000.0", func(t *testing.T) {
 		e := echo.New()
 		body := new(bytes.Buffer)
@@ -832,11 +1232,11 @@ func TestTaxCVSCalculate(t *testing.T) {
 		assert.Equal(t, expected, got)
 	})
 
-	t.Run("Test tax calculate csv with Wrong filename", func(t *testing.T) {
+	t.Run("Test tax calculate csv with any filename", func(t *testing.T) {
 		e := echo.New()
 		body := new(bytes.Buffer)
 		writer := multipart.NewWriter(body)
-		part, err := writer.CreateFormFile("taxFile", "taxesza.csv")
+		part, err := writer.CreateFormFile("taxFile", "payroll-2567-03.csv")
 		if err != nil {
 			t.Errorf("create form file error: %v", err)
 		}
@@ -849,9 +1249,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 
 		c := e.NewContext(req, rec)
 
-		stubTax := StubTax{
-			err: echo.ErrBadRequest,
-		}
+		stubTax := StubTax{}
 
 		handler := New(&stubTax)
 		err = handler.TaxCVSCalculateHandler(c)
@@ -859,23 +1257,41 @@ func TestTaxCVSCalculate(t *testing.T) {
 		if err != nil {
 			t.Errorf("expect nil but got %v", err)
 		}
-
-		actual := rec.Body.String()
-		if rec.Code != http.StatusBadRequest {
-			t.Errorf("expect %d but got %d", http.StatusBadRequest, rec.Code)
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
 		}
-		var got map[string]interface{}
-		if err := json.Unmarshal([]byte(actual), &got); err != nil {
-			t.Errorf("expect nil but got %v", err)
+		assert.Len(t, stubTax.taxCSVRequests, 1)
+	})
+
+	t.Run("Test tax calculate with semicolon-delimited file", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "export.txt")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
 		}
+		part.Write([]byte("totalIncome;wht;donation\n500000.0;0.0;1000.0\n"))
+		writer.Close()
 
-		expected := map[string]interface{}{
-			"message": "Invalid CSV file name or file not found",
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expect nil but got %v", err)
 		}
-		assert.Equal(t, expected, got)
+		assert.Equal(t, []TaxCSVRequest{
+			{Line: 2, TotalIncome: 500000.0, Allowances: []Allowance{{AllowanceType: "donation", Amount: 1000.0}}},
+		}, stubTax.taxCSVRequests)
 	})
 
-	t.Run("Test tax CSV calculate with Wrong TotalIncome value", func(t *testing.T) {
+	t.Run("Test tax calculate with too many rows should return 413", func(t *testing.T) {
 		e := echo.New()
 		body := new(bytes.Buffer)
 		writer := multipart.NewWriter(body)
@@ -883,7 +1299,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		if err != nil {
 			t.Errorf("create form file error: %v", err)
 		}
-		part.Write([]byte("totalIncome,wht,donation\n-123.00,0.0,0.0\n"))
+		part.Write([]byte("totalIncome,wht\n1.0,0.0\n2.0,0.0\n3.0,0.0\n"))
 		writer.Close()
 
 		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
@@ -892,11 +1308,129 @@ func TestTaxCVSCalculate(t *testing.T) {
 
 		c := e.NewContext(req, rec)
 
-		stubTax := StubTax{
-			err: echo.ErrBadRequest,
-		}
-
-		handler := New(&stubTax)
+		handler := New(&StubTax{})
+		handler.SetUploadLimits(0, 2)
+		handler.TaxCVSCalculateHandler(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "Upload exceeds the maximum of 2 rows", got.Message)
+	})
+
+	t.Run("Test tax calculate with oversized file should return 413", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht\n500000.0,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.SetUploadLimits(10, 0)
+		handler.TaxCVSCalculateHandler(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("Test tax calculate with oversized chunked upload should return 413", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht\n"))
+		part.Write(bytes.Repeat([]byte("500000.0,0.0\n"), 2*multipartOverhead/13))
+		writer.Close()
+
+		size := body.Len()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.SetUploadLimits(10, 0)
+		handler.TaxCVSCalculateHandler(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Greater(t, body.Len(), size/3, "body should not be read past the limit")
+	})
+
+	t.Run("Test tax calculate with xlsx over the row limit should return 413", func(t *testing.T) {
+		var workbook bytes.Buffer
+		err := xlsx.Write(&workbook, []xlsx.Sheet{
+			{Name: "Sheet1", Rows: [][]interface{}{
+				{"totalIncome", "wht"},
+				{1.0, 0.0},
+				{2.0, 0.0},
+				{3.0, 0.0},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("write workbook error: %v", err)
+		}
+
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.xlsx")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write(workbook.Bytes())
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.SetUploadLimits(0, 2)
+		handler.TaxCVSCalculateHandler(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("Test tax CSV calculate with Wrong TotalIncome value", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht,donation\n-123.00,0.0,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			err: echo.ErrBadRequest,
+		}
+
+		handler := New(&stubTax)
 		handler.TaxCVSCalculateHandler(c)
 
 		if rec.Code != http.StatusBadRequest {
@@ -931,7 +1465,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\n123.00,-1230.0,0.0\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -976,7 +1510,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\n123.00,0.0,-1.0\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1021,7 +1555,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\nabc,def,gdf.ads\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1032,10 +1566,20 @@ func TestTaxCVSCalculate(t *testing.T) {
 		}
 
 		handler := New(&stubTaxError)
-		if err := handler.TaxCVSCalculateHandler(c); err == nil {
-			t.Errorf("expected error but got nil")
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
 		}
 
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "totalIncome must be a number", got.Message)
+
 	})
 
 	t.Run("Test tax CSV calculate with Wrong Type WHT", func(t *testing.T) {
@@ -1049,7 +1593,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\n500000.0,def,gdf\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1060,10 +1604,20 @@ func TestTaxCVSCalculate(t *testing.T) {
 		}
 
 		handler := New(&stubTaxError)
-		if err := handler.TaxCVSCalculateHandler(c); err == nil {
-			t.Errorf("expected error but got nil")
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
 		}
 
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "wht must be a number", got.Message)
+
 	})
 
 	t.Run("Test tax CSV calculate with Wrong Type Allowance", func(t *testing.T) {
@@ -1077,7 +1631,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\n500000.0,25000.0,gdf\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1088,10 +1642,20 @@ func TestTaxCVSCalculate(t *testing.T) {
 		}
 
 		handler := New(&stubTaxError)
-		if err := handler.TaxCVSCalculateHandler(c); err == nil {
-			t.Errorf("expected error but got nil")
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
 		}
 
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "donation must be a number", got.Message)
+
 	})
 
 	t.Run("Test tax CSV calculate with Wrong Header", func(t *testing.T) {
@@ -1105,7 +1669,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("asdsadsad,wht,allowances\n123.00,0.0,-1.0\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1150,7 +1714,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte(""))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1195,7 +1759,7 @@ func TestTaxCVSCalculate(t *testing.T) {
 		part.Write([]byte("totalIncome,wht,donation\n500000.0,25000.0,0.0,123,456\n"))
 		writer.Close()
 
-		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv?strict=true", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 		rec := httptest.NewRecorder()
 
@@ -1229,6 +1793,137 @@ func TestTaxCVSCalculate(t *testing.T) {
 
 	})
 
+	t.Run("Test tax CSV calculate reports invalid rows and calculates the rest", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht,donation\n500000.0,0.0,0.0\nabc,0.0,0.0\n600000.0,0.0,-1.0\n700000.0,0.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{TotalIncome: 500000.0, Tax: 29000.0},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %v", http.StatusOK, rec.Code)
+		}
+
+		var got TaxCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Len(t, stubTax.taxCSVRequests, 1)
+		assert.Equal(t, []TaxCSVRowError{
+			{Line: 3, Column: "totalIncome", Reason: "totalIncome must be a number"},
+			{Line: 4, Column: "donation", Reason: "donation amount must be equal or more than 0"},
+			{Line: 5, Reason: "wrong number of fields"},
+		}, got.Errors)
+	})
+
+	t.Run("Test tax CSV calculate reports NaN and Inf amounts as invalid rows", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht,donation\n500000,0,0\nNaN,0,0\n500000,+Inf,0\n500000,0,-Inf\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{TotalIncome: 500000.0, Tax: 29000.0},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %v", http.StatusOK, rec.Code)
+		}
+
+		var got TaxCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Len(t, stubTax.taxCSVRequests, 1)
+		assert.Equal(t, []TaxCSVRowError{
+			{Line: 3, Column: "totalIncome", Reason: "totalIncome must be a number"},
+			{Line: 4, Column: "wht", Reason: "wht must be a number"},
+			{Line: 5, Column: "donation", Reason: "donation must be a number"},
+		}, got.Errors)
+	})
+
+	t.Run("Test tax CSV calculate with a result that cannot be encoded should return 500", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("totalIncome,wht\n500000,0\n600000,0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		stubTax := StubTax{
+			taxCSVCalculate: TaxCSVResponse{
+				Taxes: []TaxCSVResponseDetail{
+					{TotalIncome: 500000.0, Tax: 29000.0},
+					{TotalIncome: 600000.0, Tax: math.NaN()},
+				},
+			},
+		}
+
+		handler := New(&stubTax)
+		if err := handler.TaxCVSCalculateHandler(c); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "Internal server error", got.Message)
+	})
+
 	t.Run("Test tax CSV calculate return InternalServerError", func(t *testing.T) {
 		e := echo.New()
 		body := new(bytes.Buffer)
@@ -1271,3 +1966,424 @@ func TestTaxCVSCalculate(t *testing.T) {
 	})
 
 }
+
+func TestRetirementTaxCalculate(t *testing.T) {
+
+	t.Run("Lump sum 1,000,000.0 with 10 years of service should return tax", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/retirement/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"lumpSum": 1000000.0,
+			"yearsOfService": 10,
+			"wht": 0.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		expected := RetirementTaxResponse{
+			LumpSum:          1000000.0,
+			ServiceDeduction: 70000.0,
+			HalfDeduction:    465000.0,
+			TaxableIncome:    465000.0,
+			Tax:              31500.0,
+		}
+
+		stubTax := StubTax{
+			retirementTaxCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.RetirementTaxCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got RetirementTaxResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Years of service 0 should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/retirement/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"lumpSum": 1000000.0,
+			"yearsOfService": 0,
+			"wht": 0.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.RetirementTaxCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "years of service must be at least 1", got.Message)
+	})
+}
+
+func TestProvisionalTaxCalculate(t *testing.T) {
+
+	t.Run("Half-year income 300,000.0 should return provisional tax", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/provisional/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"totalIncome": 300000.0,
+			"wht": 0.0,
+			"allowances": []
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		expected := TaxResponse{
+			Tax: 12000.0,
+		}
+
+		stubTax := StubTax{
+			taxCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.ProvisionalTaxCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got TaxResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Provisional tax paid on half-year calculation should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/provisional/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"totalIncome": 300000.0,
+			"wht": 0.0,
+			"provisionalTaxPaid": 1000.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.ProvisionalTaxCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "provisional tax paid is only credited on the annual calculation", got.Message)
+	})
+}
+
+func TestTaxRequestValidationIncomeSources(t *testing.T) {
+
+	t.Run("Valid income sources should return nil", func(t *testing.T) {
+		req := TaxRequest{
+			IncomeSources: []IncomeSource{
+				{PayerTaxID: "0105551234567", Income: 250000.0, Wht: 10000.0},
+			},
+		}
+
+		assert.NoError(t, TaxRequestValidation(req))
+	})
+
+	t.Run("Income source WHT more than income should return error", func(t *testing.T) {
+		req := TaxRequest{
+			IncomeSources: []IncomeSource{
+				{PayerTaxID: "0105551234567", Income: 1000.0, Wht: 2000.0},
+			},
+		}
+
+		assert.EqualError(t, TaxRequestValidation(req), "wht of payer 0105551234567 must not exceed its income")
+	})
+
+	t.Run("Invalid payer tax id should return error", func(t *testing.T) {
+		req := TaxRequest{
+			IncomeSources: []IncomeSource{
+				{PayerTaxID: "12345", Income: 1000.0},
+			},
+		}
+
+		assert.EqualError(t, TaxRequestValidation(req), "payer tax id must be 13 digits")
+	})
+}
+
+func TestGrossUpCalculate(t *testing.T) {
+
+	t.Run("Net income 500,000.0 should return grossed-up income", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/gross-up/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"netIncome": 500000.0,
+			"allowances": []
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := GrossUpResponse{
+			NetIncome:   500000.0,
+			GrossIncome: 532222.22,
+			Iterations:  6,
+			Converged:   true,
+			TaxResponse: TaxResponse{Tax: 32222.22},
+		}
+		stubTax := StubTax{
+			grossUpCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.GrossUpCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got GrossUpResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Net income -1.0 should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/gross-up/calculations", io.NopCloser(strings.NewReader(
+			`{
+			"netIncome": -1.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.GrossUpCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestChangeHomeDeduction(t *testing.T) {
+
+	t.Run("Change home loan interest deduction amount 150,000.00 should return 150,000.00", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(
+			`{
+				"amount": 150000.0
+			  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/admin/deductions/:type")
+		c.SetParamNames("type")
+		c.SetParamValues("home-loan-interest")
+
+		handler := New(&StubTax{})
+		handler.ChangeDeductionHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %v", http.StatusOK, rec.Code)
+		}
+		var got map[string]float64
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, map[string]float64{"homeLoanInterest": 150000.0}, got)
+	})
+
+	t.Run("Home loan interest allowance with negative co-borrowers should return error", func(t *testing.T) {
+		req := TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []Allowance{
+				{AllowanceType: "home-loan-interest", Amount: 50000.0, CoBorrowers: -1},
+			},
+		}
+
+		assert.EqualError(t, TaxRequestValidation(req), "co-borrowers must be equal or more than 0")
+	})
+
+	t.Run("Repeated home loan interest allowance should return error", func(t *testing.T) {
+		req := TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []Allowance{
+				{AllowanceType: "home-loan-interest", Amount: 100000.0},
+				{AllowanceType: "home-loan-interest", Amount: 100000.0},
+			},
+		}
+
+		assert.EqualError(t, TaxRequestValidation(req), "allowance type home-loan-interest must not be repeated")
+	})
+
+	t.Run("Home loan interest split into co-borrower entries should return error", func(t *testing.T) {
+		req := TaxRequest{
+			TotalIncome: 500000.0,
+			Allowances: []Allowance{
+				{AllowanceType: "home-loan-interest", Amount: 50000.0, CoBorrowers: 2},
+				{AllowanceType: "home-loan-interest", Amount: 50000.0, CoBorrowers: 2},
+			},
+		}
+
+		assert.EqualError(t, TaxRequestValidation(req), "allowance type home-loan-interest must not be repeated")
+	})
+}
+
+func TestChangeSocialSecurityRate(t *testing.T) {
+
+	t.Run("Change social security rate for 2568 should return rate", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(
+			`{
+				"rate": 0.05,
+				"minMonthlyWage": 1650.0,
+				"maxMonthlyWage": 17500.0
+			  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/admin/social-security/:year")
+		c.SetParamNames("year")
+		c.SetParamValues("2568")
+
+		handler := New(&StubTax{})
+		handler.ChangeSocialSecurityRateHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %v", http.StatusOK, rec.Code)
+		}
+		var got SocialSecurityRateRequest
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, SocialSecurityRateRequest{Rate: 0.05, MinMonthlyWage: 1650.0, MaxMonthlyWage: 17500.0}, got)
+	})
+
+	t.Run("Change social security rate with invalid year should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(
+			`{
+				"rate": 0.05,
+				"minMonthlyWage": 1650.0,
+				"maxMonthlyWage": 15000.0
+			  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/admin/social-security/:year")
+		c.SetParamNames("year")
+		c.SetParamValues("abc")
+
+		handler := New(&StubTax{})
+		handler.ChangeSocialSecurityRateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestExplanationLanguage(t *testing.T) {
+
+	t.Run("Accept-Language th-TH should return th", func(t *testing.T) {
+		assert.Equal(t, "th", explanationLanguage("th-TH,th;q=0.9,en;q=0.8"))
+	})
+
+	t.Run("Accept-Language fr then en should return en", func(t *testing.T) {
+		assert.Equal(t, "en", explanationLanguage("fr-FR, en-US;q=0.5"))
+	})
+
+	t.Run("Missing Accept-Language should return empty", func(t *testing.T) {
+		assert.Equal(t, "", explanationLanguage(""))
+	})
+}
+
+func TestChangeRoundingPolicy(t *testing.T) {
+
+	t.Run("Change rounding policy to floor-baht should return floor-baht", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/rounding-policy", io.NopCloser(strings.NewReader(
+			`{
+				"roundingPolicy": "floor-baht"
+			  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.ChangeRoundingPolicyHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %v", http.StatusOK, rec.Code)
+		}
+		var got RoundingPolicyRequest
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, RoundingPolicyRequest{RoundingPolicy: "floor-baht"}, got)
+	})
+
+	t.Run("Unknown rounding policy should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/rounding-policy", io.NopCloser(strings.NewReader(
+			`{
+				"roundingPolicy": "ceil"
+			  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.ChangeRoundingPolicyHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "rounding policy must be none, half-up-satang or floor-baht", got.Message)
+	})
+}
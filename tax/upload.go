@@ -0,0 +1,230 @@
+package tax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fnk2077/assessment-tax/pkg/xlsx"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DefaultMaxUploadSize = 50 << 20
+	DefaultMaxUploadRows = 500000
+
+	// multipartOverhead allows for the form fields and boundaries around an
+	// uploaded file when checking the size of the whole request.
+	multipartOverhead = 1 << 20
+
+	sniffSize = 4096
+)
+
+// uploadTooLargeError reports an upload over the configured limits. It is
+// answered with 413 instead of 400.
+type uploadTooLargeError struct {
+	message string
+}
+
+func (e *uploadTooLargeError) Error() string {
+	return e.message
+}
+
+func (h *Handler) errUploadSize() error {
+	return &uploadTooLargeError{message: fmt.Sprintf("Upload exceeds the maximum size of %d bytes", h.maxUploadSize)}
+}
+
+func (h *Handler) errUploadRows() error {
+	return &uploadTooLargeError{message: fmt.Sprintf("Upload exceeds the maximum of %d rows", h.maxUploadRows)}
+}
+
+// SetUploadLimits changes the maximum size in bytes and the maximum number of
+// data rows of an uploaded file. A value of 0 keeps the current limit.
+func (h *Handler) SetUploadLimits(maxSize int64, maxRows int) {
+	if maxSize > 0 {
+		h.maxUploadSize = maxSize
+	}
+	if maxRows > 0 {
+		h.maxUploadRows = maxRows
+	}
+}
+
+// uploadErrorJSON answers err from reading an upload with 413 when the upload
+// is too large and 400 otherwise.
+func uploadErrorJSON(c echo.Context, err error) error {
+	var tooLarge *uploadTooLargeError
+	if errors.As(err, &tooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+}
+
+// limitUploadBody caps how much of the request body can be read, before
+// anything parses the form. It also covers chunked requests, which have no
+// Content-Length to check up front.
+func (h *Handler) limitUploadBody(c echo.Context) error {
+	if c.Request().ContentLength > h.maxUploadSize+multipartOverhead {
+		return h.errUploadSize()
+	}
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, h.maxUploadSize+multipartOverhead)
+	return nil
+}
+
+// openedTaxUpload is an uploaded file ready to be read row by row. It must
+// be closed after use.
+type openedTaxUpload struct {
+	scanner  *taxRecordScanner
+	strict   bool
+	encoding string
+	closers  []io.Closer
+}
+
+func (u *openedTaxUpload) Close() error {
+	for i := len(u.closers) - 1; i >= 0; i-- {
+		u.closers[i].Close()
+	}
+	return nil
+}
+
+// openTaxUpload opens the file uploaded as taxFile, whatever its name, and
+// reads its header. An .xlsx workbook is recognised from its content or
+// Content-Type, anything else is read as delimited text. The returned error
+// is meant for the client.
+func (h *Handler) openTaxUpload(c echo.Context) (*openedTaxUpload, error) {
+	upload := &openedTaxUpload{}
+	if value := c.QueryParam("strict"); value != "" {
+		var err error
+		upload.strict, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("strict must be true or false")
+		}
+	}
+
+	if err := h.limitUploadBody(c); err != nil {
+		return nil, err
+	}
+
+	file, err := c.FormFile("taxFile")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, h.errUploadSize()
+	}
+	if err != nil {
+		return nil, errors.New("Invalid CSV file Key")
+	}
+	if file.Size > h.maxUploadSize {
+		return nil, h.errUploadSize()
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("Invalid CSV file name or file not found")
+	}
+	upload.closers = append(upload.closers, src)
+
+	buffered := bufio.NewReaderSize(src, sniffSize)
+	prefix, _ := buffered.Peek(sniffSize)
+	isXLSX, delimiter := sniffTaxFormat(prefix, file.Header.Get(echo.HeaderContentType))
+
+	var reader taxRecordReader
+	if isXLSX {
+		sheet, err := xlsx.OpenSheet(src, file.Size, c.FormValue("sheet"), h.maxUploadSize)
+		if err != nil {
+			upload.Close()
+		}
+		if err == xlsx.ErrSheetNotFound {
+			return nil, errors.New("Invalid Excel file: sheet not found")
+		}
+		if err == xlsx.ErrTooLarge {
+			return nil, h.errUploadSize()
+		}
+		if err != nil {
+			return nil, errors.New("Invalid Excel file")
+		}
+		upload.closers = append(upload.closers, sheet)
+		reader = &xlsxRecordReader{sheet: sheet, tooLarge: h.errUploadSize()}
+	} else {
+		var text io.Reader
+		text, upload.encoding, err = newTaxFileReader(src)
+		if err != nil {
+			upload.Close()
+			return nil, errors.New("Invalid CSV file")
+		}
+		reader = newCSVRecordReader(text, delimiter)
+	}
+
+	upload.scanner, err = newTaxRecordScanner(reader)
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+	return upload, nil
+}
+
+// readTaxUpload reads every row of the file uploaded as taxFile. The returned
+// error is meant for the client.
+func (h *Handler) readTaxUpload(c echo.Context) (taxUpload, error) {
+	opened, err := h.openTaxUpload(c)
+	if err != nil {
+		return taxUpload{}, err
+	}
+	defer opened.Close()
+
+	upload, err := readTaxRecords(opened.scanner, opened.strict, h.maxUploadRows)
+	if err == errTooManyRows {
+		return taxUpload{}, h.errUploadRows()
+	}
+	upload.encoding = opened.encoding
+	return upload, err
+}
+
+func newCSVRecordReader(r io.Reader, delimiter rune) csvRecordReader {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	return csvRecordReader{reader: reader}
+}
+
+// sniffTaxFormat tells from the first bytes of an upload and its Content-Type
+// whether it is an .xlsx workbook and, if not, which delimiter separates its
+// fields. The delimiter is the comma, tab or semicolon found most often in
+// the header line outside quotes, the comma when none is found.
+func sniffTaxFormat(prefix []byte, contentType string) (bool, rune) {
+	if bytes.HasPrefix(prefix, []byte("PK\x03\x04")) || strings.HasPrefix(contentType, xlsxContentType) {
+		return true, 0
+	}
+	if strings.HasPrefix(contentType, "text/tab-separated-values") {
+		return false, '\t'
+	}
+
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, r := range string(prefix) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' || r == '\r' {
+			break
+		}
+		if r == ',' || r == '\t' || r == ';' {
+			counts[r]++
+		}
+	}
+
+	delimiter := ','
+	for _, r := range []rune{'\t', ';'} {
+		if counts[r] > counts[delimiter] {
+			delimiter = r
+		}
+	}
+	return false, delimiter
+}
@@ -0,0 +1,58 @@
+package tax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffTaxFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		prefix        string
+		contentType   string
+		wantXLSX      bool
+		wantDelimiter rune
+	}{
+		{"comma", "totalIncome,wht\n500000.0,0.0\n", "text/csv", false, ','},
+		{"tab", "totalIncome\twht\n", "", false, '\t'},
+		{"semicolon", "totalIncome;wht;donation\n", "", false, ';'},
+		{"semicolon with quoted comma", "\"name, full\";totalIncome;wht\n", "", false, ';'},
+		{"tab-separated content type", "totalIncome\n", "text/tab-separated-values", false, '\t'},
+		{"xlsx content", "PK\x03\x04rest", "application/octet-stream", true, 0},
+		{"xlsx content type", "", xlsxContentType, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isXLSX, delimiter := sniffTaxFormat([]byte(tt.prefix), tt.contentType)
+
+			assert.Equal(t, tt.wantXLSX, isXLSX)
+			assert.Equal(t, tt.wantDelimiter, delimiter)
+		})
+	}
+}
+
+func BenchmarkReadTaxRecords(b *testing.B) {
+	var file strings.Builder
+	file.WriteString("employeeId,totalIncome,wht,donation,k-receipt\n")
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&file, "E%06d,\"%s\",%d,%d,0\n", i, "1,250,000.00", i%5000, i%100000)
+	}
+	content := file.String()
+
+	b.Run("100k rows", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			scanner, err := newTaxRecordScanner(newCSVRecordReader(strings.NewReader(content), ','))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := readTaxRecords(scanner, false, DefaultMaxUploadRows); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(100000*b.N)/b.Elapsed().Seconds(), "rows/s")
+	})
+}
@@ -0,0 +1,129 @@
+package tax
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WithholdingCalculateHandler calculates tax to withhold on a single payment.
+//
+// @Summary Calculate withholding tax on a payment
+// @Description Look up the withholding rate for a payment type and calculate the tax to withhold
+// @Tags withholding
+// @Accept json
+// @Produce json
+// @Param request body WithholdingRequest true "Payment data"
+// @Success 200 {object} WithholdingResponse "Returns the withholding calculation"
+// @Router /tax/withholdings [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) WithholdingCalculateHandler(c echo.Context) error {
+	var req WithholdingRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid request body"})
+	}
+
+	err := WithholdingRequestValidation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	resp, err := h.store.WithholdingCalculate(req)
+	if errors.Is(err, ErrNotFound) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid payment type"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// WithholdingCSVCalculateHandler calculates withholding tax from CSV file.
+//
+// @Summary Calculate withholding tax from CSV file
+// @Description Calculate withholding tax for every payment in a CSV file with paymentType and amount columns
+// @Tags withholding
+// @Accept multipart/form-data
+// @Param paymentFile formData file true "CSV file containing payments"
+// @Success 200 {object} WithholdingCSVResponse "Returns the withholding calculations and totals"
+// @Router /tax/withholdings/upload-csv [post]
+// @Failure 400 {object} Err "Bad Request"
+// @Failure 500 {object} Err "Internal Server Error"
+func (h *Handler) WithholdingCSVCalculateHandler(c echo.Context) error {
+	var withholdingRequests []WithholdingRequest
+
+	file, err := c.FormFile("paymentFile")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file Key"})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file name or file not found"})
+	}
+	defer src.Close()
+
+	reader := csv.NewReader(src)
+
+	header, err := reader.Read()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file: missing header"})
+	}
+
+	expectedHeader := []string{"paymentType", "amount"}
+	if !reflect.DeepEqual(header, expectedHeader) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file: incorrect header format"})
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: "Invalid CSV file"})
+		}
+
+		amount, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: "amount must be a number"})
+		}
+
+		req := WithholdingRequest{
+			PaymentType: record[0],
+			Amount:      amount,
+		}
+		if err := WithholdingRequestValidation(req); err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		}
+		withholdingRequests = append(withholdingRequests, req)
+	}
+
+	withholdingCSVResponse, err := h.store.WithholdingCSVCalculate(withholdingRequests)
+	if errors.Is(err, ErrNotFound) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid payment type"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, withholdingCSVResponse)
+}
+
+func WithholdingRequestValidation(req WithholdingRequest) error {
+	if req.PaymentType == "" {
+		return errors.New("payment type is required")
+	}
+	if req.Amount < 0.0 {
+		return errors.New("amount must be equal or more than 0")
+	}
+
+	return nil
+}
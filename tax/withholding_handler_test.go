@@ -0,0 +1,157 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithholdingCalculate(t *testing.T) {
+
+	t.Run("Service payment 10,000.0 should return WHT 300.0", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/withholdings", io.NopCloser(strings.NewReader(
+			`{
+			"paymentType": "service",
+			"amount": 10000.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := WithholdingResponse{
+			PaymentType: "service",
+			Amount:      10000.0,
+			Rate:        0.03,
+			Wht:         300.0,
+			NetPayment:  9700.0,
+		}
+		stubTax := StubTax{
+			withholdingCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err := handler.WithholdingCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got WithholdingResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Unknown payment type should return error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/withholdings", io.NopCloser(strings.NewReader(
+			`{
+			"paymentType": "gift",
+			"amount": 10000.0
+		  }`,
+		)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubError := StubTax{err: ErrNotFound}
+		handler := New(&stubError)
+		handler.WithholdingCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "invalid payment type", got.Message)
+	})
+}
+
+func TestWithholdingCSVCalculate(t *testing.T) {
+
+	t.Run("Withholding CSV with service and rent payments should return totals", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("paymentFile", "payments.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("paymentType,amount\nservice,10000.0\nrent,20000.0\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/withholdings/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := WithholdingCSVResponse{
+			TotalAmount: 30000.0,
+			TotalWht:    1300.0,
+			Withholdings: []WithholdingResponse{
+				{PaymentType: "service", Amount: 10000.0, Rate: 0.03, Wht: 300.0, NetPayment: 9700.0},
+				{PaymentType: "rent", Amount: 20000.0, Rate: 0.05, Wht: 1000.0, NetPayment: 19000.0},
+			},
+		}
+		stubTax := StubTax{
+			withholdingCSVCalculate: expected,
+		}
+
+		handler := New(&stubTax)
+		err = handler.WithholdingCSVCalculateHandler(c)
+		if err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got WithholdingCSVResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expect nil but got %v", err)
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Withholding CSV with non-numeric amount should return error", func(t *testing.T) {
+		e := echo.New()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("paymentFile", "payments.csv")
+		if err != nil {
+			t.Errorf("create form file error: %v", err)
+		}
+		part.Write([]byte("paymentType,amount\nservice,abc\n"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tax/withholdings/upload-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubTax{})
+		handler.WithholdingCSVCalculateHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %v", http.StatusBadRequest, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("error decoding response body: %v", err)
+		}
+		assert.Equal(t, "amount must be a number", got.Message)
+	})
+}
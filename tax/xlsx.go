@@ -0,0 +1,149 @@
+package tax
+
+import (
+	"encoding/csv"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/fnk2077/assessment-tax/pkg/xlsx"
+	"github.com/labstack/echo/v4"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// xlsxRecordReader reads the rows of a sheet with the same rules as a CSV
+// file: the first row is the header and no row may be wider than it. A
+// workbook over the size limit fails with tooLarge.
+type xlsxRecordReader struct {
+	sheet    *xlsx.SheetReader
+	tooLarge error
+	width    int
+	started  bool
+}
+
+func (r *xlsxRecordReader) Read() ([]string, int, error) {
+	row, err := r.sheet.Next()
+	if err == xlsx.ErrTooLarge {
+		return nil, 0, r.tooLarge
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !r.started {
+		r.started = true
+		r.width = len(row.Cells)
+		return row.Cells, row.Number, nil
+	}
+
+	// Blank trailing cells are padding, but a value beyond the header is a
+	// misplaced column.
+	record := make([]string, r.width)
+	copy(record, row.Cells)
+	for i := r.width; i < len(row.Cells); i++ {
+		if row.Cells[i] != "" {
+			return record, row.Number, &csv.ParseError{StartLine: row.Number, Line: row.Number, Err: csv.ErrFieldCount}
+		}
+	}
+	return record, row.Number, nil
+}
+
+// writeTaxXLSX sends a workbook with a results sheet, laid out like the CSV
+// results, and an errors sheet listing the rows that were not calculated.
+// Rows read from an uploaded file start with their line on both sheets, as
+// the results sheet leaves out the rows that were not calculated.
+func writeTaxXLSX(c echo.Context, results taxResults, withLevels bool) error {
+	levels, err := taxLevelColumns(results.rows, withLevels)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, xlsxContentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="taxes.xlsx"`)
+	c.Response().WriteHeader(http.StatusOK)
+	writer := xlsx.NewWriter(c.Response())
+
+	if err := writer.StartSheet("results"); err != nil {
+		return err
+	}
+	headerRow := []interface{}{}
+	if results.lines {
+		headerRow = append(headerRow, "line")
+	}
+	for _, name := range append(append(append([]string{}, results.header...), "tax", "taxRefund"), levels...) {
+		headerRow = append(headerRow, name)
+	}
+	if err := writer.WriteRow(headerRow); err != nil {
+		return err
+	}
+
+	// Amounts go back as numbers so that the workbook can be worked with
+	// and uploaded again; everything else is kept as the text it was.
+	numeric := make([]bool, len(results.header))
+	for i, name := range results.header {
+		numeric[i] = isTaxCSVAmountColumn(name)
+	}
+
+	cells := make([]interface{}, 0, len(results.header)+3+len(levels))
+	err = results.rows(func(row taxCSVRow, detail *TaxCSVResponseDetail) error {
+		if detail == nil {
+			return nil
+		}
+
+		cells = cells[:0]
+		if results.lines {
+			cells = append(cells, float64(detail.Line))
+		}
+		for i := range results.header {
+			value := ""
+			if i < len(row.record) {
+				value = row.record[i]
+			}
+			cells = append(cells, xlsxCell(value, numeric[i]))
+		}
+		cells = append(cells, detail.Tax, detail.TaxRefund)
+		for i := range levels {
+			if i < len(detail.TaxLevels) {
+				cells = append(cells, detail.TaxLevels[i].Tax)
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		return writer.WriteRow(cells)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writer.StartSheet("errors"); err != nil {
+		return err
+	}
+	if err := writer.WriteRow([]interface{}{"line", "column", "reason"}); err != nil {
+		return err
+	}
+	err = results.rows(func(row taxCSVRow, _ *TaxCSVResponseDetail) error {
+		if row.err == nil {
+			return nil
+		}
+		return writer.WriteRow([]interface{}{float64(row.err.Line), row.err.Column, row.err.Reason})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// xlsxCell returns value as a number when numeric is set and it reads as one,
+// and as text otherwise.
+func xlsxCell(value string, numeric bool) interface{} {
+	if !numeric {
+		return value
+	}
+	amount, err := strconv.ParseFloat(normalizeAmount(value), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return value
+	}
+	return amount
+}